@@ -0,0 +1,97 @@
+package gconf
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Binding 绑定到配置的结构体
+// 每次重新加载配置后都会重新解析结构体，并在通知回调之前原子地替换为新值；
+// 解析失败时保留上一次成功解析的值。Load 无需加锁，可以在多个 goroutine 中并发调用
+type Binding struct {
+	g     *Gconf
+	typ   reflect.Type
+	value atomic.Value
+
+	mu  sync.Mutex
+	err error
+}
+
+// Bind 将配置绑定到 ptr 指向的结构体类型，返回的 Binding 随配置重新加载自动更新
+// ptr 只用于确定结构体类型（例如 &AppConfig{}），每次解析都使用新的零值，默认值应通过 SetDefault 设置。
+// 首次解析失败时返回错误
+func (g *Gconf) Bind(ptr interface{}) (*Binding, error) {
+	t := reflect.TypeOf(ptr)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("gconf: Bind 的参数必须是结构体指针")
+	}
+
+	b := &Binding{g: g, typ: t.Elem()}
+	v, err := b.decode()
+	if err != nil {
+		return nil, err
+	}
+	b.value.Store(v)
+
+	r := g
+	if g.parent != nil {
+		r = g.parent
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.bindings = append(r.bindings, b)
+	}
+	return b, nil
+}
+
+// Load 返回最近一次成功解析的结构体指针，类型与 Bind 的参数相同
+func (b *Binding) Load() interface{} {
+	return b.value.Load()
+}
+
+// Err 返回最近一次重新解析的错误，成功时返回 nil
+func (b *Binding) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// decode 将当前配置解析到新的结构体
+func (b *Binding) decode() (interface{}, error) {
+	v := reflect.New(b.typ).Interface()
+	if err := b.g.Unmarshal(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// refresh 重新解析结构体，失败时保留旧值
+func (b *Binding) refresh() {
+	v, err := b.decode()
+	if err != nil {
+		if b.g.options.Debug {
+			log.Printf("[gconf] 重新解析绑定的结构体 %s 失败，继续使用旧值: %v", b.typ, err)
+		}
+	} else {
+		b.value.Store(v)
+	}
+
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+}
+
+// refreshBindings 重新解析所有绑定的结构体
+func (g *Gconf) refreshBindings() {
+	g.mu.RLock()
+	bindings := g.bindings
+	g.mu.RUnlock()
+
+	for _, b := range bindings {
+		b.refresh()
+	}
+}
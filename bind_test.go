@@ -0,0 +1,82 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type bindConfig struct {
+	Server struct {
+		Host string
+		Port int
+	}
+}
+
+func TestBind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(WithConfigPaths(dir))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.SetDefault("server.host", "localhost")
+
+	if _, err := conf.Bind(bindConfig{}); err == nil {
+		t.Error("非指针参数应返回错误")
+	}
+
+	b, err := conf.Bind(&bindConfig{})
+	if err != nil {
+		t.Fatalf("绑定失败: %v", err)
+	}
+	first := b.Load().(*bindConfig)
+	if first.Server.Host != "localhost" || first.Server.Port != 8080 {
+		t.Errorf("初始解析结果不正确: %+v", first)
+	}
+
+	var seen int
+	conf.OnChange(func(e ChangeEvent) {
+		seen = b.Load().(*bindConfig).Server.Port
+	})
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if seen != 9090 {
+		t.Errorf("回调中应读取到新值，得到 %d", seen)
+	}
+	if first.Server.Port != 8080 {
+		t.Error("重新解析不应修改旧值")
+	}
+
+	writeFile(t, file, "server:\n  port: abc\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if b.Err() == nil {
+		t.Error("解析失败时 Err 应返回错误")
+	}
+	if v := b.Load().(*bindConfig).Server.Port; v != 9090 {
+		t.Errorf("解析失败时应保留旧值: 期望 9090，得到 %d", v)
+	}
+
+	writeFile(t, file, "server:\n  port: 10000\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if b.Err() != nil || b.Load().(*bindConfig).Server.Port != 10000 {
+		t.Errorf("恢复后应解析新值: %v, %+v", b.Err(), b.Load())
+	}
+}
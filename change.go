@@ -0,0 +1,186 @@
+package gconf
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeType 配置项的变化类型
+type ChangeType int
+
+const (
+	// ChangeAdded 新增的配置项
+	ChangeAdded ChangeType = iota + 1
+	// ChangeRemoved 被删除的配置项
+	ChangeRemoved
+	// ChangeModified 值被修改的配置项
+	ChangeModified
+)
+
+// String 返回变化类型的名称
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// KeyChange 单个配置项的变化
+type KeyChange struct {
+	// 配置键（以 . 分隔的完整路径）
+	Key string
+	// 变化类型
+	Type ChangeType
+	// 变化前的值（新增时为 nil）
+	OldValue interface{}
+	// 变化后的值（删除时为 nil）
+	NewValue interface{}
+}
+
+// ChangeEvent 配置变化事件，描述一次重新加载前后配置的差异
+type ChangeEvent struct {
+	// 触发本次重新加载的文件系统事件
+	Event fsnotify.Event
+	// 发生变化的配置项，按键名排序
+	Changes []KeyChange
+}
+
+// HasChanges 判断本次事件是否包含配置项变化
+func (e ChangeEvent) HasChanges() bool {
+	return len(e.Changes) > 0
+}
+
+// Keys 返回所有发生变化的配置键
+func (e ChangeEvent) Keys() []string {
+	keys := make([]string, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		keys = append(keys, c.Key)
+	}
+	return keys
+}
+
+// Change 获取指定配置键的变化
+func (e ChangeEvent) Change(key string) (KeyChange, bool) {
+	for _, c := range e.Changes {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return KeyChange{}, false
+}
+
+// Added 返回新增的配置项
+func (e ChangeEvent) Added() []KeyChange {
+	return e.byType(ChangeAdded)
+}
+
+// Removed 返回被删除的配置项
+func (e ChangeEvent) Removed() []KeyChange {
+	return e.byType(ChangeRemoved)
+}
+
+// Modified 返回值被修改的配置项
+func (e ChangeEvent) Modified() []KeyChange {
+	return e.byType(ChangeModified)
+}
+
+// Filter 返回只包含匹配任一 pattern 的配置项变化的事件，pattern 规则见 MatchKey
+func (e ChangeEvent) Filter(patterns ...string) ChangeEvent {
+	filtered := ChangeEvent{Event: e.Event}
+	for _, c := range e.Changes {
+		for _, pattern := range patterns {
+			if MatchKey(pattern, c.Key) {
+				filtered.Changes = append(filtered.Changes, c)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func (e ChangeEvent) byType(t ChangeType) []KeyChange {
+	var changes []KeyChange
+	for _, c := range e.Changes {
+		if c.Type == t {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// MatchKey 判断配置键是否匹配 pattern
+// pattern 按 . 分段逐段匹配，每段支持 *、?、[...] 等 glob 语法；
+// pattern 匹配完后剩余的键视为其子树，例如 "database" 和 "database.*"
+// 都匹配 "database.host" 与 "database.pool.size"，匹配时不区分大小写
+func MatchKey(pattern, key string) bool {
+	patternParts := strings.Split(strings.ToLower(pattern), ".")
+	keyParts := strings.Split(strings.ToLower(key), ".")
+	if len(patternParts) > len(keyParts) {
+		return false
+	}
+	for i, p := range patternParts {
+		if ok, err := path.Match(p, keyParts[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// diffSettings 比较两棵配置树，返回按键名排序的叶子节点差异
+func diffSettings(oldSettings, newSettings map[string]interface{}) []KeyChange {
+	oldFlat := flattenSettings(oldSettings)
+	newFlat := flattenSettings(newSettings)
+
+	changes := make([]KeyChange, 0)
+	for key, oldValue := range oldFlat {
+		newValue, ok := newFlat[key]
+		if !ok {
+			changes = append(changes, KeyChange{Key: key, Type: ChangeRemoved, OldValue: oldValue})
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, KeyChange{Key: key, Type: ChangeModified, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, newValue := range newFlat {
+		if _, ok := oldFlat[key]; !ok {
+			changes = append(changes, KeyChange{Key: key, Type: ChangeAdded, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// flattenSettings 将嵌套的配置树展开为 "a.b.c" 形式的叶子节点映射
+func flattenSettings(settings map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", settings)
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, settings map[string]interface{}) {
+	for k, v := range settings {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+			flattenInto(flat, key, sub)
+			continue
+		}
+		flat[key] = v
+	}
+}
@@ -0,0 +1,202 @@
+package gconf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffSettings(t *testing.T) {
+	oldSettings := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "localhost",
+			"port": 8080,
+		},
+		"debug": true,
+	}
+	newSettings := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host": "0.0.0.0",
+			"port": 8080,
+		},
+		"name": "app",
+	}
+
+	changes := diffSettings(oldSettings, newSettings)
+	if len(changes) != 3 {
+		t.Fatalf("期望 3 个变化，得到 %d: %+v", len(changes), changes)
+	}
+
+	ev := ChangeEvent{Changes: changes}
+	if c, ok := ev.Change("debug"); !ok || c.Type != ChangeRemoved || c.OldValue != true {
+		t.Errorf("debug 应为删除: %+v", c)
+	}
+	if c, ok := ev.Change("name"); !ok || c.Type != ChangeAdded || c.NewValue != "app" {
+		t.Errorf("name 应为新增: %+v", c)
+	}
+	if c, ok := ev.Change("server.host"); !ok || c.Type != ChangeModified ||
+		c.OldValue != "localhost" || c.NewValue != "0.0.0.0" {
+		t.Errorf("server.host 应为修改: %+v", c)
+	}
+	if _, ok := ev.Change("server.port"); ok {
+		t.Error("server.port 未变化，不应出现在变化列表中")
+	}
+}
+
+// writeFile 以原子替换的方式写入测试配置文件，避免监听到写了一半的文件
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("替换文件失败: %v", err)
+	}
+}
+
+func TestOnChangeReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  port: 8080\nname: app\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  port: 9090\n")
+
+	select {
+	case ev := <-events:
+		c, ok := ev.Change("server.port")
+		if !ok || c.OldValue != 8080 || c.NewValue != 9090 {
+			t.Errorf("server.port 变化不正确: %+v", ev.Changes)
+		}
+		if c, ok := ev.Change("name"); !ok || c.Type != ChangeRemoved {
+			t.Errorf("name 应为删除: %+v", ev.Changes)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待配置变化事件超时")
+	}
+}
+
+func TestMatchKey(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"database.*", "database.host", true},
+		{"database.*", "database.pool.size", true},
+		{"database", "database.host", true},
+		{"database.*", "database", false},
+		{"server.port", "server.port", true},
+		{"server.port", "server.host", false},
+		{"server.port", "server", false},
+		{"*.port", "redis.port", true},
+		{"db?.host", "db1.host", true},
+		{"Server.Port", "server.port", true},
+	}
+	for _, c := range cases {
+		if got := MatchKey(c.pattern, c.key); got != c.want {
+			t.Errorf("MatchKey(%q, %q) = %v, 期望 %v", c.pattern, c.key, got, c.want)
+		}
+	}
+}
+
+func TestOnKeyChange(t *testing.T) {
+	conf, _ := New()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnKeyChange("database.*", func(e ChangeEvent) {
+		events <- e
+	})
+
+	conf.notify(ChangeEvent{Changes: []KeyChange{
+		{Key: "server.port", Type: ChangeModified, OldValue: 8080, NewValue: 9090},
+	}})
+	conf.notify(ChangeEvent{Changes: []KeyChange{
+		{Key: "database.host", Type: ChangeModified, OldValue: "a", NewValue: "b"},
+		{Key: "server.port", Type: ChangeModified, OldValue: 9090, NewValue: 8080},
+	}})
+
+	select {
+	case ev := <-events:
+		if len(ev.Changes) != 1 || ev.Changes[0].Key != "database.host" {
+			t.Errorf("回调应只收到 database.host 的变化: %+v", ev.Changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("等待配置变化事件超时")
+	}
+
+	select {
+	case ev := <-events:
+		t.Errorf("不相关的变化不应触发回调: %+v", ev.Changes)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReloadDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "port: 1\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+		WithReloadDebounce(200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	for i := 2; i <= 4; i++ {
+		writeFile(t, file, fmt.Sprintf("port: %d\n", i))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case ev := <-events:
+		c, ok := ev.Change("port")
+		if !ok || c.OldValue != 1 || c.NewValue != 4 {
+			t.Errorf("合并后的变化不正确: %+v", ev.Changes)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待配置变化事件超时")
+	}
+
+	// 内容未变化的写入不应触发回调
+	writeFile(t, file, "port: 4\n")
+	select {
+	case ev := <-events:
+		t.Errorf("内容未变化不应触发回调: %+v", ev)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,130 @@
+package gconf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentAccess 并发读写与文件重新加载的压力测试，建议配合 -race 运行
+func TestConcurrentAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 0\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.OnChange(func(e ChangeEvent) {
+		_ = conf.GetInt("server.port")
+	})
+
+	// 全局实例，替换为独立的实例，结束后恢复，避免影响其他测试
+	global, err := New()
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer global.Close()
+	defaultInstanceMu.Lock()
+	saved := defaultInstance
+	defaultInstance = global
+	defaultInstanceMu.Unlock()
+	defer func() {
+		defaultInstanceMu.Lock()
+		defaultInstance = saved
+		defaultInstanceMu.Unlock()
+	}()
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// 文件重新加载
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tmp := file + ".tmp"
+		for i := 1; i <= 20; i++ {
+			// 不能在其他 goroutine 中调用 t.Fatal，因此不使用 writeFile
+			if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("server:\n  port: %d\n", i)), 0644); err != nil {
+				t.Errorf("写入文件失败: %v", err)
+				return
+			}
+			if err := os.Rename(tmp, file); err != nil {
+				t.Errorf("替换文件失败: %v", err)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	// 写入
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				conf.Set(fmt.Sprintf("writer.%d", w), i)
+				conf.SetDefault(fmt.Sprintf("default.%d", w), i)
+			}
+		}(w)
+	}
+
+	// 读取
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				_ = conf.GetInt("server.port")
+				_ = conf.GetString("writer.0")
+				_ = conf.IsSet("default.1")
+				_ = conf.AllKeys()
+				_ = conf.AllSettings()
+				_ = conf.Snapshot().GetInt("server.port")
+				var cfg struct {
+					Server struct {
+						Port int `mapstructure:"port"`
+					} `mapstructure:"server"`
+				}
+				if err := conf.Unmarshal(&cfg); err != nil {
+					t.Errorf("Unmarshal 失败: %v", err)
+				}
+			}
+		}()
+	}
+
+	// 全局实例
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			Set("global.concurrent", i)
+			_ = GetInt("global.concurrent")
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("并发测试超时")
+	}
+}
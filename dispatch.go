@@ -0,0 +1,285 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeHandler 可返回错误的配置变化回调
+type ChangeHandler func(ChangeEvent) error
+
+// DispatchMode 配置变化回调的执行方式
+type DispatchMode int
+
+const (
+	// DispatchParallel 并发执行所有回调（默认）
+	DispatchParallel DispatchMode = iota
+	// DispatchSequential 按注册顺序依次执行回调
+	DispatchSequential
+)
+
+// ErrHandlerTimeout 回调执行超过 HandlerTimeout
+var ErrHandlerTimeout = errors.New("gconf: 配置变化回调执行超时")
+
+// HandlerError 单个回调执行失败
+type HandlerError struct {
+	// 回调的注册序号，从 0 开始
+	Index int
+	// 回调返回的错误、panic 转换的错误或 ErrHandlerTimeout
+	Err error
+	// 回调 panic 时的值，未 panic 时为 nil
+	Panic interface{}
+	// 回调 panic 时的调用栈
+	Stack []byte
+}
+
+// Error 实现 error 接口
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("回调 #%d: %v", e.Index, e.Err)
+}
+
+// Unwrap 返回失败原因
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// DispatchError 一次配置变化通知中执行失败的所有回调
+type DispatchError struct {
+	// 本次通知的配置变化事件
+	Event ChangeEvent
+	// 执行失败的回调，按注册顺序排列
+	Errors []*HandlerError
+}
+
+// Error 实现 error 接口
+func (e *DispatchError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("gconf: %d 个配置变化回调执行失败: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// WithDispatchMode 设置配置变化回调的执行方式，默认并发执行
+func WithDispatchMode(mode DispatchMode) Option {
+	return func(o *Options) {
+		o.DispatchMode = mode
+	}
+}
+
+// WithMaxConcurrency 设置并发执行回调时的最大并发数，0 表示不限制
+func WithMaxConcurrency(n int) Option {
+	return func(o *Options) {
+		o.MaxConcurrency = n
+	}
+}
+
+// WithHandlerTimeout 设置单个回调的超时时间，0 表示不限制
+// 超时的回调记为 ErrHandlerTimeout，不再等待它结束，但它仍会在后台继续执行
+func WithHandlerTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.HandlerTimeout = d
+	}
+}
+
+// WithOnHandlerError 设置回调执行失败时的回调，参数为 *DispatchError
+func WithOnHandlerError(fn func(error)) Option {
+	return func(o *Options) {
+		o.OnHandlerError = fn
+	}
+}
+
+// OnChangeE 注册可返回错误的配置变化回调函数
+// 回调返回的错误和 panic 会被收集到 DispatchError 中，不会影响其他回调
+func (g *Gconf) OnChangeE(fn ChangeHandler) {
+	if g.parent != nil {
+		g.parent.OnChangeE(func(e ChangeEvent) error {
+			if e = trimEvent(e, g.prefix); e.HasChanges() {
+				return fn(e)
+			}
+			return nil
+		})
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.onChangeHandlers = append(g.onChangeHandlers, fn)
+}
+
+// LastHandlerError 返回最近一次配置变化通知的执行结果
+// 所有回调都成功时返回 nil，否则返回 *DispatchError
+func (g *Gconf) LastHandlerError() error {
+	if g.parent != nil {
+		return g.parent.LastHandlerError()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.lastHandlerErr
+}
+
+// eventQueue 后台重新加载的通知队列，按加载顺序依次通知事件
+// 同一时间最多只有一个 goroutine 执行通知，队列为空时退出
+type eventQueue struct {
+	mu      sync.Mutex
+	events  []ChangeEvent
+	running bool
+}
+
+// enqueue 将事件加入通知队列，需要时启动执行通知的 goroutine
+func (g *Gconf) enqueue(ev ChangeEvent) {
+	q := &g.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.events = append(q.events, ev)
+	if !q.running {
+		q.running = true
+		go g.drain()
+	}
+}
+
+// drain 按顺序通知队列中的事件，直到队列为空或配置实例关闭
+func (g *Gconf) drain() {
+	q := &g.queue
+	for {
+		q.mu.Lock()
+		closed := false
+		select {
+		case <-g.done:
+			closed = true
+		default:
+		}
+		if closed || len(q.events) == 0 {
+			q.events = nil
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		ev := q.events[0]
+		q.events = q.events[1:]
+		q.mu.Unlock()
+
+		_ = g.notify(ev)
+	}
+}
+
+// notify 按配置的执行方式调用所有注册的回调，等待它们结束并收集失败
+func (g *Gconf) notify(ev ChangeEvent) error {
+	g.mu.RLock()
+	if g.closed {
+		g.mu.RUnlock()
+		return nil
+	}
+	handlers := g.onChangeHandlers
+	g.mu.RUnlock()
+
+	g.publish(ev)
+
+	var failures []*HandlerError
+	if g.options.DispatchMode == DispatchSequential {
+		for i, handler := range handlers {
+			if herr := g.runHandler(i, handler, ev); herr != nil {
+				failures = append(failures, herr)
+			}
+		}
+	} else {
+		failures = g.runParallel(handlers, ev)
+	}
+
+	var err error
+	if len(failures) > 0 {
+		err = &DispatchError{Event: ev, Errors: failures}
+		if g.options.Debug {
+			log.Printf("[gconf] %v", err)
+		}
+		if g.options.OnHandlerError != nil {
+			g.callErrorHandler(g.options.OnHandlerError, err)
+		}
+	}
+
+	g.mu.Lock()
+	g.lastHandlerErr = err
+	g.mu.Unlock()
+	return err
+}
+
+// runParallel 并发执行回调，MaxConcurrency 大于 0 时限制同时执行的数量
+func (g *Gconf) runParallel(handlers []ChangeHandler, ev ChangeEvent) []*HandlerError {
+	results := make([]*HandlerError, len(handlers))
+
+	var sem chan struct{}
+	if g.options.MaxConcurrency > 0 {
+		sem = make(chan struct{}, g.options.MaxConcurrency)
+	}
+
+	var wg sync.WaitGroup
+	for i, handler := range handlers {
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		wg.Add(1)
+		go func(i int, handler ChangeHandler) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			results[i] = g.runHandler(i, handler, ev)
+		}(i, handler)
+	}
+	wg.Wait()
+
+	var failures []*HandlerError
+	for _, r := range results {
+		if r != nil {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
+
+// runHandler 执行单个回调，设置了 HandlerTimeout 时最多等待该时长
+func (g *Gconf) runHandler(index int, handler ChangeHandler, ev ChangeEvent) *HandlerError {
+	timeout := g.options.HandlerTimeout
+	if timeout <= 0 {
+		return callHandler(index, handler, ev)
+	}
+
+	result := make(chan *HandlerError, 1)
+	go func() {
+		result <- callHandler(index, handler, ev)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case herr := <-result:
+		return herr
+	case <-timer.C:
+		return &HandlerError{Index: index, Err: ErrHandlerTimeout}
+	}
+}
+
+// callHandler 执行回调并把 panic 转换为错误
+func callHandler(index int, handler ChangeHandler, ev ChangeEvent) (herr *HandlerError) {
+	defer func() {
+		if p := recover(); p != nil {
+			herr = &HandlerError{
+				Index: index,
+				Err:   fmt.Errorf("panic: %v", p),
+				Panic: p,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	if err := handler(ev); err != nil {
+		return &HandlerError{Index: index, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestDispatchErrors(t *testing.T) {
+	var reported error
+	conf, _ := New(WithOnHandlerError(func(err error) {
+		reported = err
+	}))
+
+	var called int32
+	errBad := errors.New("bad handler")
+	conf.OnChangeE(func(e ChangeEvent) error {
+		panic("boom")
+	})
+	conf.OnChangeE(func(e ChangeEvent) error {
+		return errBad
+	})
+	conf.OnChange(func(e ChangeEvent) {
+		atomic.AddInt32(&called, 1)
+	})
+
+	err := conf.notify(ChangeEvent{})
+	if atomic.LoadInt32(&called) != 1 {
+		t.Error("其他回调的失败不应影响正常回调")
+	}
+
+	var dispatchErr *DispatchError
+	if !errors.As(err, &dispatchErr) || len(dispatchErr.Errors) != 2 {
+		t.Fatalf("期望包含 2 个失败的 DispatchError，得到 %v", err)
+	}
+	if herr := dispatchErr.Errors[0]; herr.Index != 0 || herr.Panic != "boom" || len(herr.Stack) == 0 {
+		t.Errorf("panic 应被恢复并记录调用栈: %+v", herr)
+	}
+	if herr := dispatchErr.Errors[1]; herr.Index != 1 || !errors.Is(herr, errBad) {
+		t.Errorf("应记录回调返回的错误: %+v", herr)
+	}
+	if reported != err {
+		t.Errorf("OnHandlerError 应收到 DispatchError，得到 %v", reported)
+	}
+	if conf.LastHandlerError() != err {
+		t.Errorf("LastHandlerError 应返回最近一次的失败，得到 %v", conf.LastHandlerError())
+	}
+
+	if err := conf.notify(ChangeEvent{}); err == nil {
+		t.Error("回调仍然失败时应返回错误")
+	}
+}
+
+func TestDispatchSequential(t *testing.T) {
+	conf, _ := New(WithDispatchMode(DispatchSequential))
+
+	var mu sync.Mutex
+	order := make([]int, 0)
+	for i := 0; i < 5; i++ {
+		i := i
+		conf.OnChange(func(e ChangeEvent) {
+			// 先注册的回调耗时更长，仍应先执行完
+			time.Sleep(time.Duration(5-i) * time.Millisecond)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+
+	if err := conf.notify(ChangeEvent{}); err != nil {
+		t.Fatalf("通知失败: %v", err)
+	}
+	for i, v := range order {
+		if v != i {
+			t.Fatalf("回调应按注册顺序执行: %v", order)
+		}
+	}
+	if conf.LastHandlerError() != nil {
+		t.Errorf("回调全部成功时 LastHandlerError 应为 nil，得到 %v", conf.LastHandlerError())
+	}
+}
+
+func TestDispatchMaxConcurrency(t *testing.T) {
+	conf, _ := New(WithMaxConcurrency(2))
+
+	var running, peak int32
+	for i := 0; i < 6; i++ {
+		conf.OnChange(func(e ChangeEvent) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	if err := conf.notify(ChangeEvent{}); err != nil {
+		t.Fatalf("通知失败: %v", err)
+	}
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("同时执行的回调数不应超过 2，得到 %d", p)
+	}
+}
+
+func TestDispatchTimeout(t *testing.T) {
+	conf, _ := New(WithHandlerTimeout(20 * time.Millisecond))
+
+	release := make(chan struct{})
+	defer close(release)
+	conf.OnChange(func(e ChangeEvent) {
+		<-release
+	})
+
+	start := time.Now()
+	err := conf.notify(ChangeEvent{})
+	var dispatchErr *DispatchError
+	if !errors.As(err, &dispatchErr) || !errors.Is(dispatchErr.Errors[0], ErrHandlerTimeout) {
+		t.Fatalf("期望 ErrHandlerTimeout，得到 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超时的回调不应阻塞通知: %v", elapsed)
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "a: 0\n")
+
+	conf, err := New(WithConfigPaths(dir), WithDispatchMode(DispatchSequential))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	values := make(chan interface{}, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		c, _ := e.Change("a")
+		// 第一个事件处理较慢，之后的事件仍应排在它后面
+		if c.NewValue == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		values <- c.NewValue
+	})
+
+	// 模拟多次快速的文件事件
+	for i := 1; i <= 3; i++ {
+		writeFile(t, file, fmt.Sprintf("a: %d\n", i))
+		conf.scheduleReload(fsnotify.Event{Name: file, Op: fsnotify.Write})
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case v := <-values:
+			if v != i {
+				t.Fatalf("事件应按重新加载的顺序通知: 第 %d 个事件得到 %v", i, v)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待配置变化事件超时")
+		}
+	}
+}
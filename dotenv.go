@@ -0,0 +1,343 @@
+package gconf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// WithDotEnv 读取 .env 文件作为环境变量层，后面的文件覆盖前面的文件，文件不存在时跳过
+// 文件中的变量与真实的环境变量一样按 AutomaticEnv、EnvPrefix、EnvKeyReplacer 和 BindEnv 的规则对应配置项，
+// 但不会修改进程的环境变量；默认真实的环境变量优先，见 WithDotEnvOverride。
+// 支持的语法：
+//
+//	# 注释
+//	export APP_NAME=demo            # export 前缀和行尾注释
+//	APP_DB_PASS="p@ss\nword"        # 双引号中支持 \n、\t、\"、\\、\$ 转义和变量展开，可以跨行
+//	APP_GREETING='hello ${literal}' # 单引号中的内容原样保留，可以跨行
+//	APP_DSN=postgres://${APP_DB_USER:-root}@$APP_DB_HOST/db
+//
+// 变量展开按相同的优先级查找真实的环境变量和已读取的 .env 变量，WithInterpolation 展开的 ${VAR} 同样可以引用 .env 变量。
+// 与 AutomaticEnv 一样，Get*、IsSet 也能读取只在 .env 文件中出现的配置项；.env 文件在创建实例和重新加载时读取
+func WithDotEnv(files ...string) Option {
+	return func(o *Options) {
+		o.DotEnvFiles = append(o.DotEnvFiles, files...)
+	}
+}
+
+// WithDotEnvOverride 设置 .env 文件中的变量是否覆盖同名的真实环境变量（默认不覆盖）
+func WithDotEnvOverride(override bool) Option {
+	return func(o *Options) {
+		o.DotEnvOverride = override
+	}
+}
+
+// dotEnvVar .env 文件中的一个变量
+type dotEnvVar struct {
+	value string
+	file  string
+	line  int
+}
+
+// parseDotEnv 解析 .env 文件内容，将变量写入 vars，同名变量覆盖已有的值
+func parseDotEnv(file string, data []byte, vars map[string]dotEnvVar, override bool) error {
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return fmt.Errorf("解析 %s:%d 失败: 缺少 =", file, lineNo)
+		}
+		name := strings.TrimSpace(line[:eq])
+		if !validEnvName(name) {
+			return fmt.Errorf("解析 %s:%d 失败: 无效的变量名 %q", file, lineNo, name)
+		}
+
+		raw := strings.TrimSpace(line[eq+1:])
+		var value string
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			quote := raw[0]
+			body := raw[1:]
+			end := closingQuote(body, quote)
+			// 跨行的值，直到找到结束引号
+			for end < 0 && i+1 < len(lines) {
+				i++
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+			if end < 0 {
+				return fmt.Errorf("解析 %s:%d 失败: 引号未闭合", file, lineNo)
+			}
+			if rest := strings.TrimSpace(body[end+1:]); rest != "" && rest[0] != '#' {
+				return fmt.Errorf("解析 %s:%d 失败: 引号之后有多余的内容 %q", file, lineNo, rest)
+			}
+			if quote == '\'' {
+				value = body[:end]
+			} else {
+				value = expandDotEnv(body[:end], true, vars, override)
+			}
+		} else {
+			if i := strings.Index(raw, " #"); i >= 0 {
+				raw = strings.TrimSpace(raw[:i])
+			}
+			value = expandDotEnv(raw, false, vars, override)
+		}
+		vars[name] = dotEnvVar{value: value, file: file, line: lineNo}
+	}
+	return nil
+}
+
+// closingQuote 返回结束引号的位置，双引号中跳过转义的字符，不存在时返回 -1
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandDotEnv 展开 $NAME、${NAME} 和 ${NAME:-默认值}，escapes 为 true 时处理双引号中的转义
+func expandDotEnv(s string, escapes bool, vars map[string]dotEnvVar, override bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escapes && c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			expr := s[i+2 : end]
+			name, def, hasDef := expr, "", false
+			if j := strings.Index(expr, ":-"); j >= 0 {
+				name, def, hasDef = expr[:j], expr[j+2:], true
+			}
+			v := lookupValue(strings.TrimSpace(name), vars, override)
+			if v == "" && hasDef {
+				v = expandDotEnv(def, escapes, vars, override)
+			}
+			b.WriteString(v)
+			i = end
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && (s[j] == '_' || isAlnum(s[j])) {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString(lookupValue(s[i+1:j], vars, override))
+		i = j - 1
+	}
+	return b.String()
+}
+
+// validEnvName 判断是否为有效的变量名：字母、数字、下划线和 .，不以数字开头
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '_' && name[i] != '.' && !isAlnum(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// envNames 按 viper 的规则返回配置项对应的环境变量名（已应用替换规则）：先 AutomaticEnv，再 BindEnv 绑定的变量
+func envNames(options *Options, bindings [][]string, key string) []string {
+	names := make([]string, 0, 2)
+	if options.AutomaticEnv {
+		names = append(names, envName(options, key))
+	}
+	for _, keys := range bindings {
+		if strings.ToLower(keys[0]) != key {
+			continue
+		}
+		if len(keys) > 1 {
+			names = append(names, keys[1])
+		} else {
+			names = append(names, envName(options, key))
+		}
+	}
+	for i, name := range names {
+		if options.EnvKeyReplacer != nil {
+			names[i] = options.EnvKeyReplacer.Replace(name)
+		}
+	}
+	return names
+}
+
+// envName 返回配置键对应的环境变量名（替换规则之前）
+func envName(options *Options, key string) string {
+	if options.EnvPrefix != "" {
+		return strings.ToUpper(options.EnvPrefix + "_" + key)
+	}
+	return strings.ToUpper(key)
+}
+
+// lookupEnvVar 按优先级在真实的环境变量和 .env 变量中查找变量，真实的环境变量为空时视为未设置
+func lookupEnvVar(name string, vars map[string]dotEnvVar, override bool) (string, bool) {
+	dv, inDotEnv := vars[name]
+	value, inEnv := os.LookupEnv(name)
+	if inDotEnv && (override || value == "") {
+		return dv.value, true
+	}
+	return value, inEnv
+}
+
+// lookupValue 返回变量的值，未设置时为空字符串
+func lookupValue(name string, vars map[string]dotEnvVar, override bool) string {
+	v, _ := lookupEnvVar(name, vars, override)
+	return v
+}
+
+// lookupDotEnvValue 查找 viper 中不存在的配置项对应的 .env 变量，调用方需持有读锁
+// bindDotEnv 只能绑定已知的配置项，其他配置项与 AutomaticEnv 读取真实的环境变量一样在读取时按名称查找
+func (g *Gconf) lookupDotEnvValue(key string) (string, bool) {
+	if len(g.dotenv) == 0 {
+		return "", false
+	}
+	_, v, ok := g.lookupDotEnv(g.resolveAlias(strings.ToLower(key)))
+	return v.value, ok
+}
+
+// bindDotEnv 将 .env 变量绑定到 viper 实例中对应的已知配置项，vars 指向实例当前的 .env 变量
+// viper 只从进程环境变量读取环境变量，因此借用 flag 层：绑定的值在生效时（WithDotEnvOverride，或者真实的环境变量未设置）
+// 视为已设置的 flag，优先级高于真实的环境变量和配置文件；配置项同时绑定了 flag 时，命令行中设置的 flag 仍然优先。
+// viper 无法解除绑定，因此绑定的值在读取时从 vars 中查找，重新加载后删除的变量不再生效
+func bindDotEnv(v *viper.Viper, options *Options, bindings [][]string, vars *map[string]dotEnvVar) {
+	if len(*vars) == 0 {
+		return
+	}
+	flags := make(map[string]viper.FlagValue)
+	for _, f := range flagBindings(options) {
+		flags[f.key] = f.value
+	}
+	for _, key := range v.AllKeys() {
+		names := envNames(options, bindings, key)
+		for _, name := range names {
+			if _, ok := (*vars)[name]; ok {
+				_ = v.BindFlagValue(key, &dotEnvFlag{
+					names:    names,
+					vars:     vars,
+					flag:     flags[key],
+					override: options.DotEnvOverride,
+				})
+				break
+			}
+		}
+	}
+}
+
+// dotEnvFlag 将配置项对应的 .env 变量适配为 viper.FlagValue
+type dotEnvFlag struct {
+	// 配置项对应的所有环境变量名
+	names []string
+	vars  *map[string]dotEnvVar
+	// 同一配置项绑定的 flag，可能为 nil
+	flag     viper.FlagValue
+	override bool
+}
+
+// lookup 返回配置项对应的第一个 .env 变量
+func (f *dotEnvFlag) lookup() (string, string, bool) {
+	for _, name := range f.names {
+		if v, ok := (*f.vars)[name]; ok {
+			return name, v.value, true
+		}
+	}
+	return "", "", false
+}
+
+// active 判断 .env 变量是否生效：变量存在，并且覆盖真实的环境变量或者真实的环境变量都未设置
+func (f *dotEnvFlag) active() bool {
+	if _, _, ok := f.lookup(); !ok {
+		return false
+	}
+	if f.override {
+		return true
+	}
+	for _, name := range f.names {
+		if os.Getenv(name) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// useFlag 判断是否使用同一配置项绑定的 flag：flag 在命令行中设置，或者 .env 变量未生效时使用 flag 的默认值
+func (f *dotEnvFlag) useFlag() bool {
+	return f.flag != nil && (f.flag.HasChanged() || !f.active())
+}
+
+func (f *dotEnvFlag) HasChanged() bool {
+	return (f.flag != nil && f.flag.HasChanged()) || f.active()
+}
+
+func (f *dotEnvFlag) Name() string {
+	if f.flag != nil {
+		return f.flag.Name()
+	}
+	name, _, _ := f.lookup()
+	return name
+}
+
+func (f *dotEnvFlag) ValueString() string {
+	if f.useFlag() {
+		return f.flag.ValueString()
+	}
+	_, value, _ := f.lookup()
+	return value
+}
+
+func (f *dotEnvFlag) ValueType() string {
+	if f.useFlag() {
+		return f.flag.ValueType()
+	}
+	return "string"
+}
@@ -0,0 +1,144 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	os.Setenv("GCONF_DOTENV_HOME", "/home/app")
+	defer os.Unsetenv("GCONF_DOTENV_HOME")
+
+	data := `# 注释
+export NAME=demo # 行尾注释
+EMPTY=
+HOST=localhost
+URL=http://${HOST}:${PORT:-8080}/$NAME
+DIR=$GCONF_DOTENV_HOME/data
+DOUBLE="line1\nline2 \"quoted\" \$HOST ${HOST}"
+SINGLE='raw ${HOST} \n'
+MULTI="first
+second"
+CERT='-----BEGIN-----
+abc
+-----END-----'
+`
+	vars := make(map[string]dotEnvVar)
+	if err := parseDotEnv(".env", []byte(data), vars, false); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	tests := map[string]string{
+		"NAME":   "demo",
+		"EMPTY":  "",
+		"URL":    "http://localhost:8080/demo",
+		"DIR":    "/home/app/data",
+		"DOUBLE": "line1\nline2 \"quoted\" $HOST localhost",
+		"SINGLE": `raw ${HOST} \n`,
+		"MULTI":  "first\nsecond",
+		"CERT":   "-----BEGIN-----\nabc\n-----END-----",
+	}
+	for name, want := range tests {
+		if v, ok := vars[name]; !ok || v.value != want {
+			t.Errorf("%s: 期望 %q，得到 %q", name, want, v.value)
+		}
+	}
+	if v := vars["HOST"]; v.file != ".env" || v.line != 4 {
+		t.Errorf("行号不正确: %+v", v)
+	}
+
+	for _, bad := range []string{"NOVALUE", "1BAD=x", "OPEN=\"abc", "TRAIL=\"abc\" x"} {
+		if err := parseDotEnv(".env", []byte(bad), make(map[string]dotEnvVar), false); err == nil {
+			t.Errorf("%q 应返回错误", bad)
+		}
+	}
+}
+
+func TestDotEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  host: localhost\n  port: 8080\ndatabase:\n  password: \"\"\n  dsn: \"db://${GCONFDOT_DATABASE_PASSWORD}@${server.host}\"\n")
+	dotenv := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	writeFile(t, dotenv, "GCONFDOT_SERVER_PORT=9090\nGCONFDOT_SERVER_HOST=dotenv.example.com\nGCONFDOT_DATABASE_PASSWORD=secret\nGCONFDOT_SECRET=x\n")
+	writeFile(t, local, "GCONFDOT_SERVER_PORT=7070\n")
+
+	os.Setenv("GCONFDOT_SERVER_HOST", "env.example.com")
+	defer os.Unsetenv("GCONFDOT_SERVER_HOST")
+
+	newConf := func(opts ...Option) *Gconf {
+		t.Helper()
+		conf, err := New(append([]Option{
+			WithConfigPaths(dir),
+			WithAutomaticEnv(true),
+			WithEnvPrefix("GCONFDOT"),
+			WithEnvKeyReplacer(".", "_"),
+			WithDotEnv(dotenv, local, filepath.Join(dir, ".env.missing")),
+		}, opts...)...)
+		if err != nil {
+			t.Fatalf("创建配置实例失败: %v", err)
+		}
+		return conf
+	}
+
+	conf := newConf(WithInterpolation(true))
+	defer conf.Close()
+	if _, ok := os.LookupEnv("GCONFDOT_SERVER_PORT"); ok {
+		t.Error("不应修改进程的环境变量")
+	}
+	if v := conf.GetInt("server.port"); v != 7070 {
+		t.Errorf("后面的 .env 文件应覆盖前面的文件: 得到 %d", v)
+	}
+	if v := conf.GetString("server.host"); v != "env.example.com" {
+		t.Errorf("默认真实的环境变量优先: 得到 %s", v)
+	}
+	if v := conf.GetString("database.password"); v != "secret" {
+		t.Errorf(".env 变量应覆盖配置文件: 得到 %s", v)
+	}
+	if v := conf.GetString("database.dsn"); v != "db://secret@env.example.com" {
+		t.Errorf("变量展开应能引用 .env 变量: 得到 %s", v)
+	}
+	if v := conf.GetString("secret"); v != "x" || !conf.IsSet("secret") {
+		t.Errorf("只在 .env 中出现的配置项应与真实的环境变量一样可以读取: 得到 %q", v)
+	}
+	var cfg struct {
+		Server struct{ Port int }
+	}
+	if err := conf.Unmarshal(&cfg); err != nil || cfg.Server.Port != 7070 {
+		t.Errorf("Unmarshal 应使用 .env 变量: %+v, %v", cfg, err)
+	}
+	if e := conf.Explain("server.host"); e.Source == nil || e.Source.Layer != "env" || len(e.Shadowed) < 1 || e.Shadowed[0].Layer != "dotenv" {
+		t.Errorf("Explain 结果不正确: %s", e)
+	}
+	if e := conf.Explain("server.port"); e.Source == nil || e.Source.File != local || e.Source.Line != 1 {
+		t.Errorf("Explain 应指出 .env 文件和行号: %s", e)
+	}
+
+	conf.SetDefault("log.level", "info")
+	writeFile(t, local, "GCONFDOT_LOG_LEVEL=debug\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("重新加载后删除的 .env 变量不应生效: 得到 %d", v)
+	}
+	if v := conf.GetString("log.level"); v != "debug" {
+		t.Errorf("默认值对应的 .env 变量应生效: 得到 %s", v)
+	}
+
+	override := newConf(WithDotEnvOverride(true))
+	defer override.Close()
+	if v := override.GetString("server.host"); v != "dotenv.example.com" {
+		t.Errorf("WithDotEnvOverride 时 .env 变量应覆盖真实的环境变量: 得到 %s", v)
+	}
+	if layers := override.Layers(); len(layers) < 2 || layers[0] != "dotenv" || layers[1] != "env" {
+		t.Errorf("Layers 结果不正确: %v", layers)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"time"
@@ -18,10 +19,10 @@ type AppConfig struct {
 	} `mapstructure:"app"`
 
 	Server struct {
-		Host         string        `mapstructure:"host"`
-		Port         int           `mapstructure:"port"`
-		ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-		WriteTimeout time.Duration `mapstructure:"write_timeout"`
+		Host         string        `mapstructure:"host" usage:"服务监听地址"`
+		Port         int           `mapstructure:"port" usage:"服务监听端口"`
+		ReadTimeout  time.Duration `mapstructure:"read_timeout" usage:"读取超时"`
+		WriteTimeout time.Duration `mapstructure:"write_timeout" usage:"写入超时"`
 	} `mapstructure:"server"`
 
 	Database struct {
@@ -52,8 +53,16 @@ func main() {
 	// 示例2: 高级用法 - 创建独立实例
 	fmt.Println("=== 示例2: 高级用法 ===")
 
+	// 根据配置结构体生成命令行参数，例如 -server-port 9090、-database-max-conns 50
+	flagOption, err := gconf.RegisterFlags(flag.CommandLine, &AppConfig{})
+	if err != nil {
+		log.Fatalf("注册命令行参数失败: %v", err)
+	}
+	flag.Parse()
+
 	// 创建配置实例，使用链式配置
 	conf, err := gconf.New(
+		flagOption, // 命令行参数覆盖配置文件
 		gconf.WithConfigName("app"),
 		gconf.WithConfigType("yaml"),
 		gconf.WithConfigPaths(".", "./config", "/etc/myapp"),
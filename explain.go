@@ -0,0 +1,260 @@
+package gconf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Origin 配置项在某一配置层中的值
+type Origin struct {
+	// 配置层名称，与 Layers 的返回值一致：override、flag、env、dotenv、default、flag-default 或配置源名称
+	Layer string
+	// 配置层中的原始值
+	Value interface{}
+	// 配置项所在的配置文件或 .env 文件，其他配置层为空
+	File string
+	// 配置项在配置文件中的行号（从 1 开始，按键名查找，无法确定时为 0）
+	Line int
+	// 环境变量名，只在 env 和 dotenv 层设置
+	EnvVar string
+	// flag 名称，只在 flag 和 flag-default 层设置
+	Flag string
+}
+
+// String 返回配置层的可读描述，例如 "file:/etc/app/config.yaml:12"、"env GCONF_SERVER_PORT"、
+// "dotenv GCONF_SERVER_PORT (.env:3)"、"flag --port"
+func (o Origin) String() string {
+	if o.EnvVar != "" {
+		if o.File != "" {
+			return fmt.Sprintf("%s %s (%s:%d)", o.Layer, o.EnvVar, o.File, o.Line)
+		}
+		return o.Layer + " " + o.EnvVar
+	}
+	if o.Flag != "" {
+		return o.Layer + " --" + o.Flag
+	}
+	if o.File == "" {
+		return o.Layer
+	}
+	loc := o.File
+	if o.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", o.File, o.Line)
+	}
+	if o.Layer == "file:"+o.File {
+		return "file:" + loc
+	}
+	return fmt.Sprintf("%s (%s)", o.Layer, loc)
+}
+
+// Explanation 配置项的来源
+type Explanation struct {
+	// 配置键
+	Key string
+	// 生效的值，与 Get 的返回值一致（启用变量展开时为展开后的值）
+	Value interface{}
+	// 生效的值所在的配置层，配置项不存在时为 nil
+	Source *Origin
+	// 被生效的值覆盖的低优先级配置层中的值，按优先级从高到低排列
+	Shadowed []Origin
+}
+
+// String 返回配置项及其来源的可读描述
+func (e Explanation) String() string {
+	if e.Source == nil {
+		return fmt.Sprintf("%s: <未设置>", e.Key)
+	}
+	s := fmt.Sprintf("%s = %v  # %s", e.Key, e.Value, e.Source)
+	for _, o := range e.Shadowed {
+		s += fmt.Sprintf("; 覆盖 %s = %v", o, o.Value)
+	}
+	return s
+}
+
+// Explain 说明配置项的值来自哪一个配置层，以及它覆盖了哪些低优先级配置层中的值
+// 配置层的优先级与 Layers 一致：Set 的值、命令行中设置的 flag、环境变量和 .env 变量、配置源（后面的优先）、默认值、flag 的默认值
+func (g *Gconf) Explain(key string) Explanation {
+	if g.parent != nil {
+		e := g.parent.Explain(g.fullKey(key))
+		e.Key = key
+		return e
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.explain(key)
+}
+
+// ExplainAll 说明所有配置项的来源，按配置键排序
+func (g *Gconf) ExplainAll() []Explanation {
+	keys := g.AllKeys()
+	sort.Strings(keys)
+	explanations := make([]Explanation, 0, len(keys))
+	for _, key := range keys {
+		explanations = append(explanations, g.Explain(key))
+	}
+	return explanations
+}
+
+// explain 按优先级从高到低收集配置项在各配置层中的值，调用方需持有读锁
+func (g *Gconf) explain(key string) Explanation {
+	e := Explanation{Key: key, Value: g.get(key)}
+	key = g.resolveAlias(strings.ToLower(key))
+
+	origins := make([]Origin, 0)
+	if v, ok := lookupSettings(g.layers.overrides, key); ok {
+		origins = append(origins, Origin{Layer: "override", Value: v})
+	}
+	f, hasFlag := g.lookupFlag(key)
+	if hasFlag && f.HasChanged() {
+		origins = append(origins, Origin{Layer: "flag", Value: f.ValueString(), Flag: f.Name()})
+	}
+	env := make([]Origin, 0, 2)
+	if name, v, ok := g.lookupEnv(key); ok {
+		env = append(env, Origin{Layer: "env", Value: v, EnvVar: name})
+	}
+	if name, v, ok := g.lookupDotEnv(key); ok {
+		dotenv := Origin{Layer: "dotenv", Value: v.value, EnvVar: name, File: v.file, Line: v.line}
+		if g.options.DotEnvOverride {
+			env = append([]Origin{dotenv}, env...)
+		} else {
+			env = append(env, dotenv)
+		}
+	}
+	origins = append(origins, env...)
+	for i := len(g.sourceEntries) - 1; i >= 0; i-- {
+		entry := g.sourceEntries[i]
+		if v, ok := lookupKey(entry.settings, key); ok {
+			origins = append(origins, Origin{
+				Layer: entry.name,
+				Value: v,
+				File:  entry.file,
+				Line:  findKeyLine(entry.data, key),
+			})
+		}
+	}
+	if v, ok := lookupSettings(g.layers.defaults, key); ok {
+		origins = append(origins, Origin{Layer: "default", Value: v})
+	}
+	if hasFlag && !f.HasChanged() {
+		origins = append(origins, Origin{Layer: "flag-default", Value: f.ValueString(), Flag: f.Name()})
+	}
+
+	if len(origins) > 0 {
+		e.Source = &origins[0]
+		e.Shadowed = origins[1:]
+	}
+	return e
+}
+
+// resolveAlias 将别名转换为实际的配置键
+func (g *Gconf) resolveAlias(key string) string {
+	for _, s := range g.layers.aliases {
+		if s.key == strings.ToLower(key) {
+			return g.resolveAlias(strings.ToLower(s.value.(string)))
+		}
+	}
+	return key
+}
+
+// lookupEnv 按 viper 的规则查找配置项对应的真实环境变量：先 AutomaticEnv，再 BindEnv 绑定的变量
+func (g *Gconf) lookupEnv(key string) (string, string, bool) {
+	for _, name := range envNames(g.options, g.layers.envBindings, key) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			return name, v, true
+		}
+	}
+	return "", "", false
+}
+
+// lookupDotEnv 查找配置项对应的 .env 变量
+func (g *Gconf) lookupDotEnv(key string) (string, dotEnvVar, bool) {
+	for _, name := range envNames(g.options, g.layers.envBindings, key) {
+		if v, ok := g.dotenv[name]; ok {
+			return name, v, true
+		}
+	}
+	return "", dotEnvVar{}, false
+}
+
+// lookupSettings 在 Set 或 SetDefault 的记录中查找配置项，后面的记录优先
+func lookupSettings(settings []setting, key string) (interface{}, bool) {
+	for i := len(settings) - 1; i >= 0; i-- {
+		s := settings[i]
+		if s.key == key {
+			return s.value, true
+		}
+		if strings.HasPrefix(key, s.key+".") {
+			if m, ok := s.value.(map[string]interface{}); ok {
+				if v, ok := lookupKey(m, strings.TrimPrefix(key, s.key+".")); ok {
+					return v, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookupKey 在配置树中按 . 分隔的配置键查找值，键名不区分大小写
+func lookupKey(settings map[string]interface{}, key string) (interface{}, bool) {
+	var value interface{} = settings
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, part) {
+				value, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// findKeyLine 在配置文件内容中查找配置键所在的行，无法确定时返回 0
+// 依次查找配置键的每一段，后一段只在前一段之后查找；适用于 YAML、TOML、JSON、INI 等常见格式，
+// 结果只作为提示，例如键名同时出现在注释中时可能不准确
+func findKeyLine(data []byte, key string) int {
+	if len(data) == 0 {
+		return 0
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	line := 0
+	for _, part := range strings.Split(key, ".") {
+		found := false
+		for i := line; i < len(lines); i++ {
+			if lineHasKey(string(lines[i]), part) {
+				line, found = i, true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	return line + 1
+}
+
+// lineHasKey 判断一行是否定义了指定的键，例如 `port:`、`"port":`、`port =`、`[port]`
+func lineHasKey(line, key string) bool {
+	line = strings.ToLower(strings.TrimSpace(line))
+	line = strings.TrimLeft(line, "-[ ")
+	for _, quote := range []string{"", `"`, "'"} {
+		if !strings.HasPrefix(line, quote+key+quote) {
+			continue
+		}
+		rest := strings.TrimSpace(line[len(quote+key+quote):])
+		if rest == "" || rest[0] == ':' || rest[0] == '=' || rest[0] == ']' || rest[0] == '.' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package gconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "# 服务配置\nserver:\n  host: file.local\n  port: 8080\ndatabase:\n  port: 3306\n")
+	confDir := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fragment := filepath.Join(confDir, "10-server.yaml")
+	writeFile(t, fragment, "server:\n  port: 8081\n")
+
+	os.Setenv("GCONFEXPLAIN_SERVER_PORT", "9090")
+	defer os.Unsetenv("GCONFEXPLAIN_SERVER_PORT")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithConfigDir(confDir, "*.yaml"),
+		WithAutomaticEnv(true),
+		WithEnvPrefix("GCONFEXPLAIN"),
+		WithEnvKeyReplacer(".", "_"),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.SetDefault("server.port", 80)
+	conf.SetDefault("server.timeout", "5s")
+
+	e := conf.Explain("server.port")
+	if e.Source == nil || e.Source.Layer != "env" || e.Source.EnvVar != "GCONFEXPLAIN_SERVER_PORT" {
+		t.Fatalf("server.port 应来自环境变量: %+v", e.Source)
+	}
+	if len(e.Shadowed) != 3 {
+		t.Fatalf("应覆盖片段、主配置和默认值中的值: %+v", e.Shadowed)
+	}
+	if o := e.Shadowed[0]; o.File != fragment || o.Line != 2 || o.Value != 8081 {
+		t.Errorf("第一个被覆盖的值应来自片段第 2 行: %+v", o)
+	}
+	if o := e.Shadowed[1]; o.File != file || o.Line != 4 || o.Value != 8080 {
+		t.Errorf("第二个被覆盖的值应来自主配置第 4 行: %+v", o)
+	}
+	if o := e.Shadowed[2]; o.Layer != "default" || o.Value != 80 {
+		t.Errorf("最后被覆盖的值应为默认值: %+v", o)
+	}
+
+	conf.Set("server.host", "override.local")
+	if e := conf.Explain("server.host"); e.Source.Layer != "override" || e.Value != "override.local" || len(e.Shadowed) != 1 {
+		t.Errorf("server.host 应来自 Set: %+v", e)
+	}
+	if e := conf.Explain("server.timeout"); e.Source.Layer != "default" {
+		t.Errorf("server.timeout 应来自默认值: %+v", e.Source)
+	}
+	if e := conf.Explain("missing"); e.Source != nil {
+		t.Errorf("不存在的配置项不应有来源: %+v", e.Source)
+	}
+	if e := conf.Sub("database").Explain("port"); e.Key != "port" || e.Source.Line != 6 {
+		t.Errorf("视图应说明前缀下配置项的来源: %+v", e)
+	}
+
+	all := conf.ExplainAll()
+	if len(all) != len(conf.AllKeys()) {
+		t.Errorf("ExplainAll 应包含所有配置项: %d", len(all))
+	}
+	for _, e := range all {
+		if e.Key == "database.port" && !strings.Contains(e.String(), file+":6") {
+			t.Errorf("描述应包含文件和行号: %s", e)
+		}
+	}
+}
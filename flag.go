@@ -0,0 +1,456 @@
+package gconf
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// WithFlagSet 将标准库的 flag.FlagSet 作为配置层
+// 命令行中设置的 flag 覆盖配置文件和环境变量（Set 的值除外），未设置的 flag 的默认值作为优先级最低的默认值；
+// flag 名称按 WithFlagKey、WithFlagKeyReplacer 转换为配置键，应在 New 之前调用 fs.Parse
+func WithFlagSet(fs *flag.FlagSet) Option {
+	return func(o *Options) {
+		o.FlagSets = append(o.FlagSets, fs)
+	}
+}
+
+// WithPFlagSet 将 pflag.FlagSet 作为配置层，规则与 WithFlagSet 相同
+func WithPFlagSet(fs *pflag.FlagSet) Option {
+	return func(o *Options) {
+		o.PFlagSets = append(o.PFlagSets, fs)
+	}
+}
+
+// WithFlagKeyReplacer 设置 flag 名称到配置键的替换规则，例如 ("-", ".") 将 server-port 转换为 server.port
+// 未设置时 flag 名称直接作为配置键
+func WithFlagKeyReplacer(oldNew ...string) Option {
+	return func(o *Options) {
+		o.FlagKeyReplacer = strings.NewReplacer(oldNew...)
+	}
+}
+
+// WithFlagKey 指定 flag 对应的配置键，优先于 WithFlagKeyReplacer
+func WithFlagKey(name, key string) Option {
+	return func(o *Options) {
+		if o.FlagKeys == nil {
+			o.FlagKeys = make(map[string]string)
+		}
+		o.FlagKeys[name] = key
+	}
+}
+
+// boundFlag 绑定到配置键的 flag
+type boundFlag struct {
+	key   string
+	value viper.FlagValue
+}
+
+// flagBindings 返回选项中所有 flag 及其对应的配置键
+func flagBindings(options *Options) []boundFlag {
+	bindings := make([]boundFlag, 0)
+	for _, fs := range options.FlagSets {
+		fs.VisitAll(func(f *flag.Flag) {
+			bindings = append(bindings, boundFlag{
+				key:   flagKey(options, f.Name),
+				value: &goFlag{fs: fs, flag: f},
+			})
+		})
+	}
+	for _, fs := range options.PFlagSets {
+		fs.VisitAll(func(f *pflag.Flag) {
+			bindings = append(bindings, boundFlag{
+				key:   flagKey(options, f.Name),
+				value: &pFlag{flag: f},
+			})
+		})
+	}
+	return bindings
+}
+
+// flagKey 将 flag 名称转换为配置键
+func flagKey(options *Options, name string) string {
+	if key, ok := options.FlagKeys[name]; ok {
+		return strings.ToLower(key)
+	}
+	key := name
+	if options.FlagKeyReplacer != nil {
+		key = options.FlagKeyReplacer.Replace(key)
+	}
+	return strings.ToLower(key)
+}
+
+// lookupFlag 查找绑定到配置键的 flag
+func (g *Gconf) lookupFlag(key string) (viper.FlagValue, bool) {
+	for _, f := range flagBindings(g.options) {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// goFlag 将标准库的 flag 适配为 viper.FlagValue
+type goFlag struct {
+	fs   *flag.FlagSet
+	flag *flag.Flag
+}
+
+// HasChanged 判断 flag 是否在命令行中设置
+func (f *goFlag) HasChanged() bool {
+	changed := false
+	f.fs.Visit(func(v *flag.Flag) {
+		if v.Name == f.flag.Name {
+			changed = true
+		}
+	})
+	return changed
+}
+
+func (f *goFlag) Name() string {
+	return f.flag.Name
+}
+
+func (f *goFlag) ValueString() string {
+	return f.flag.Value.String()
+}
+
+// ValueType 返回 viper 用于转换值的类型，只区分布尔值、整数和字符串列表，其余类型由读取方法转换
+func (f *goFlag) ValueType() string {
+	getter, ok := f.flag.Value.(flag.Getter)
+	if !ok {
+		return "string"
+	}
+	switch getter.Get().(type) {
+	case bool:
+		return "bool"
+	case int, int64, uint, uint64:
+		return "int"
+	case []string:
+		return "stringSlice"
+	}
+	return "string"
+}
+
+// pFlag 将 pflag 适配为 viper.FlagValue
+type pFlag struct {
+	flag *pflag.Flag
+}
+
+func (f *pFlag) HasChanged() bool {
+	return f.flag.Changed
+}
+
+func (f *pFlag) Name() string {
+	return f.flag.Name
+}
+
+func (f *pFlag) ValueString() string {
+	return f.flag.Value.String()
+}
+
+func (f *pFlag) ValueType() string {
+	return f.flag.Value.Type()
+}
+
+// RegisterFlags 为结构体的每个叶子字段注册一个 flag，返回将这些 flag 作为配置层的选项
+// 配置键与 Unmarshal 的规则一致（mapstructure 标签或字段名），flag 名称由配置键的每一段转换为 kebab-case 后以 - 连接，
+// 例如 Server.ReadTimeout `mapstructure:"read_timeout"` 对应配置键 server.read_timeout 和 flag server-read-timeout；
+// 字段标签 default 设置默认值（未设置时使用字段的当前值），usage 设置帮助信息，flag:"name" 指定名称，flag:"-" 跳过字段。
+// 支持字符串、布尔值、整数、浮点数、time.Duration 和 []string（以逗号分隔）字段，其他类型的字段不生成 flag。
+// 调用 fs.Parse 后将返回的选项传给 New，命令行中设置的 flag 覆盖配置文件，未设置的 flag 的默认值作为默认值
+func RegisterFlags(fs *flag.FlagSet, ptr interface{}) (Option, error) {
+	fields, err := flagFields(ptr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFlagNames(fields, func(name string) bool { return fs.Lookup(name) != nil }); err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		switch v := f.value.(type) {
+		case string:
+			fs.String(f.name, v, f.usage)
+		case bool:
+			fs.Bool(f.name, v, f.usage)
+		case int64:
+			fs.Int64(f.name, v, f.usage)
+		case uint64:
+			fs.Uint64(f.name, v, f.usage)
+		case float64:
+			fs.Float64(f.name, v, f.usage)
+		case time.Duration:
+			fs.Duration(f.name, v, f.usage)
+		case []string:
+			fs.Var(newStringSliceValue(v), f.name, f.usage)
+		}
+	}
+	return withFlagFields(func(o *Options) {
+		o.FlagSets = append(o.FlagSets, fs)
+	}, fields), nil
+}
+
+// RegisterPFlags 为结构体的每个叶子字段注册一个 pflag，规则与 RegisterFlags 相同
+func RegisterPFlags(fs *pflag.FlagSet, ptr interface{}) (Option, error) {
+	fields, err := flagFields(ptr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFlagNames(fields, func(name string) bool { return fs.Lookup(name) != nil }); err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		switch v := f.value.(type) {
+		case string:
+			fs.String(f.name, v, f.usage)
+		case bool:
+			fs.Bool(f.name, v, f.usage)
+		case int64:
+			fs.Int64(f.name, v, f.usage)
+		case uint64:
+			fs.Uint64(f.name, v, f.usage)
+		case float64:
+			fs.Float64(f.name, v, f.usage)
+		case time.Duration:
+			fs.Duration(f.name, v, f.usage)
+		case []string:
+			fs.StringSlice(f.name, v, f.usage)
+		}
+	}
+	return withFlagFields(func(o *Options) {
+		o.PFlagSets = append(o.PFlagSets, fs)
+	}, fields), nil
+}
+
+// checkFlagNames 在注册任何 flag 之前检查名称是否已定义或相互重复，失败时 FlagSet 保持不变
+func checkFlagNames(fields []flagField, defined func(name string) bool) error {
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if defined(f.name) || seen[f.name] {
+			return fmt.Errorf("flag %s 已定义", f.name)
+		}
+		seen[f.name] = true
+	}
+	return nil
+}
+
+// flagField 结构体中生成 flag 的字段
+type flagField struct {
+	// flag 名称
+	name string
+	// 配置键
+	key string
+	// 帮助信息
+	usage string
+	// 默认值，类型为 string、bool、int64、uint64、float64、time.Duration 或 []string
+	value interface{}
+}
+
+// withFlagFields 返回注册 flag 集合并设置每个 flag 对应配置键的选项
+func withFlagFields(addSet Option, fields []flagField) Option {
+	return func(o *Options) {
+		addSet(o)
+		for _, f := range fields {
+			WithFlagKey(f.name, f.key)(o)
+		}
+	}
+}
+
+// flagFields 遍历结构体指针，返回所有可以生成 flag 的叶子字段
+func flagFields(ptr interface{}) ([]flagField, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("RegisterFlags 需要结构体指针，得到 %T", ptr)
+	}
+	fields := make([]flagField, 0)
+	if err := walkFlagFields(v.Elem(), "", "", &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// walkFlagFields 递归遍历结构体字段，key 和 name 为上一层的配置键和 flag 名称
+func walkFlagFields(v reflect.Value, key, name string, fields *[]flagField) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		flagName := sf.Tag.Get("flag")
+		if flagName == "-" {
+			continue
+		}
+		tag := strings.Split(sf.Tag.Get("mapstructure"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv = reflect.New(fv.Type().Elem())
+			}
+			fv = fv.Elem()
+		}
+
+		fieldKey := strings.ToLower(tag[0])
+		if fieldKey == "" {
+			fieldKey = strings.ToLower(sf.Name)
+			if flagName == "" {
+				flagName = kebabCase(sf.Name)
+			}
+		}
+		if flagName == "" {
+			flagName = kebabCase(fieldKey)
+		}
+		if key != "" {
+			fieldKey = key + "." + fieldKey
+			flagName = name + "-" + flagName
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			// 嵌入的 squash 结构体与上一层共用前缀
+			if stringInSlice("squash", tag[1:]) {
+				if err := walkFlagFields(fv, key, name, fields); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := walkFlagFields(fv, fieldKey, flagName, fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := flagValue(fv)
+		if !ok {
+			continue
+		}
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			var err error
+			if value, err = parseFlagDefault(value, def); err != nil {
+				return fmt.Errorf("字段 %s 的默认值 %q 无效: %w", sf.Name, def, err)
+			}
+		}
+		*fields = append(*fields, flagField{
+			name:  flagName,
+			key:   fieldKey,
+			usage: sf.Tag.Get("usage"),
+			value: value,
+		})
+	}
+	return nil
+}
+
+// flagValue 将字段的当前值转换为 flag 支持的类型，不支持的类型返回 false
+func flagValue(v reflect.Value) (interface{}, bool) {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()), true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			list := make([]string, v.Len())
+			for i := range list {
+				list[i] = v.Index(i).String()
+			}
+			return list, true
+		}
+	}
+	return nil, false
+}
+
+// parseFlagDefault 按字段类型解析 default 标签
+func parseFlagDefault(value interface{}, def string) (interface{}, error) {
+	switch value.(type) {
+	case bool:
+		return cast.ToBoolE(def)
+	case int64:
+		return cast.ToInt64E(def)
+	case uint64:
+		return cast.ToUint64E(def)
+	case float64:
+		return cast.ToFloat64E(def)
+	case time.Duration:
+		return time.ParseDuration(def)
+	case []string:
+		return splitList(def), nil
+	}
+	return def, nil
+}
+
+// kebabCase 将字段名或配置键转换为 kebab-case，例如 ReadTimeout、read_timeout 都转换为 read-timeout
+func kebabCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == ' ' || r == '.':
+			b.WriteByte('-')
+		case unicode.IsUpper(r):
+			// 连续的大写字母（例如 DBHost 中的 DB）视为一个单词
+			if i > 0 && runes[i-1] != '_' && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('-')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitList 按逗号分隔字符串，去掉每一项两端的空白，空字符串返回空列表
+func splitList(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// stringSliceValue 以逗号分隔的字符串列表 flag
+type stringSliceValue struct {
+	list []string
+}
+
+func newStringSliceValue(list []string) *stringSliceValue {
+	return &stringSliceValue{list: append([]string(nil), list...)}
+}
+
+func (s *stringSliceValue) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s.list, ",")
+}
+
+// Set 解析命令行中的值，多次设置时以最后一次为准
+func (s *stringSliceValue) Set(v string) error {
+	s.list = splitList(v)
+	return nil
+}
+
+func (s *stringSliceValue) Get() interface{} {
+	return s.list
+}
@@ -0,0 +1,220 @@
+package gconf
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestFlagSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  port: 8080\n  host: localhost\nlog:\n  level: warn\n")
+
+	os.Setenv("GCONFFLAG_SERVER_HOST", "env.example.com")
+	defer os.Unsetenv("GCONFFLAG_SERVER_HOST")
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.Int("server-port", 80, "监听端口")
+	fs.String("server-host", "0.0.0.0", "监听地址")
+	fs.String("log-level", "info", "日志级别")
+	fs.Bool("debug", false, "调试模式")
+	fs.String("name", "gconf", "应用名称")
+	if err := fs.Parse([]string{"-server-port", "9090", "-server-host", "flag.example.com", "-debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithAutomaticEnv(true),
+		WithEnvPrefix("GCONFFLAG"),
+		WithEnvKeyReplacer(".", "_"),
+		WithFlagSet(fs),
+		WithFlagKeyReplacer("-", "."),
+		WithFlagKey("name", "app.name"),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("设置的 flag 应覆盖配置文件: 期望 9090，得到 %d", v)
+	}
+	if v := conf.GetString("server.host"); v != "flag.example.com" {
+		t.Errorf("设置的 flag 应覆盖环境变量: 得到 %s", v)
+	}
+	if v := conf.GetString("log.level"); v != "warn" {
+		t.Errorf("未设置的 flag 不应覆盖配置文件: 得到 %s", v)
+	}
+	if v := conf.GetString("app.name"); v != "gconf" {
+		t.Errorf("未设置的 flag 的默认值应作为默认值: 得到 %s", v)
+	}
+	if !conf.GetBool("debug") {
+		t.Error("布尔 flag 应生效")
+	}
+
+	conf.Set("server.port", 7070)
+	if v := conf.GetInt("server.port"); v != 7070 {
+		t.Errorf("Set 的值应覆盖 flag: 得到 %d", v)
+	}
+
+	e := conf.Explain("server.host")
+	if e.Source == nil || e.Source.Layer != "flag" || e.Source.Flag != "server-host" || len(e.Shadowed) != 2 {
+		t.Errorf("Explain 结果不正确: %s", e)
+	}
+	if e := conf.Explain("app.name"); e.Source == nil || e.Source.Layer != "flag-default" {
+		t.Errorf("Explain 结果不正确: %s", e)
+	}
+}
+
+func TestPFlagSet(t *testing.T) {
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	fs.Int("port", 80, "监听端口")
+	fs.StringSlice("tags", []string{"a"}, "标签")
+	if err := fs.Parse([]string{"--tags", "x,y"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(
+		WithSources(MapSource("base", map[string]interface{}{"server": map[string]interface{}{"port": 8080}})),
+		WithPFlagSet(fs),
+		WithFlagKey("port", "server.port"),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if v := conf.GetInt("server.port"); v != 8080 {
+		t.Errorf("未设置的 flag 不应覆盖配置源: 得到 %d", v)
+	}
+	if v := conf.GetStringSlice("tags"); len(v) != 2 || v[0] != "x" || v[1] != "y" {
+		t.Errorf("pflag 的切片值不正确: %v", v)
+	}
+
+	if err := fs.Set("port", "9090"); err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("设置的 flag 应覆盖配置源: 得到 %d", v)
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Host        string        `mapstructure:"host" default:"0.0.0.0" usage:"监听地址"`
+			Port        int           `mapstructure:"port" usage:"监听端口"`
+			ReadTimeout time.Duration `mapstructure:"read_timeout" default:"30s"`
+		} `mapstructure:"server"`
+		Log struct {
+			Level string `mapstructure:"level"`
+		} `mapstructure:"log"`
+		Tags     []string          `mapstructure:"tags" default:"a,b"`
+		MaxConns uint              `default:"100"`
+		Secret   string            `flag:"-"`
+		Metadata map[string]string `mapstructure:"metadata"`
+	}
+
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "config.yaml"), "server:\n  host: localhost\n  port: 8080\nlog:\n  level: warn\n")
+
+	cfg := &Config{}
+	cfg.Server.Port = 80
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	opt, err := RegisterFlags(fs, cfg)
+	if err != nil {
+		t.Fatalf("注册 flag 失败: %v", err)
+	}
+
+	for _, name := range []string{"server-host", "server-port", "server-read-timeout", "log-level", "tags", "max-conns"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("缺少 flag %s", name)
+		}
+	}
+	if fs.Lookup("secret") != nil || fs.Lookup("metadata") != nil {
+		t.Error("跳过的字段和不支持的类型不应生成 flag")
+	}
+	if f := fs.Lookup("server-host"); f.DefValue != "0.0.0.0" || f.Usage != "监听地址" {
+		t.Errorf("默认值或帮助信息不正确: %q %q", f.DefValue, f.Usage)
+	}
+	if f := fs.Lookup("server-port"); f.DefValue != "80" {
+		t.Errorf("没有 default 标签时应使用字段的当前值，得到 %q", f.DefValue)
+	}
+
+	if err := fs.Parse([]string{"-server-port", "9090", "-tags", "x, y", "-server-read-timeout", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := New(WithConfigPaths(dir), opt)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	var got Config
+	if err := conf.Unmarshal(&got); err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+	if got.Server.Port != 9090 || got.Server.Host != "localhost" || got.Server.ReadTimeout != time.Minute {
+		t.Errorf("设置的 flag 应覆盖配置文件，未设置的不应覆盖: %+v", got.Server)
+	}
+	if got.Log.Level != "warn" || got.MaxConns != 100 {
+		t.Errorf("未设置的 flag 应作为默认值: %+v", got)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "x" || got.Tags[1] != "y" {
+		t.Errorf("列表 flag 解析结果不正确: %v", got.Tags)
+	}
+
+	if _, err := RegisterFlags(fs, cfg); err == nil {
+		t.Error("重复注册 flag 应返回错误")
+	}
+	if _, err := RegisterFlags(flag.NewFlagSet("app", flag.ContinueOnError), *cfg); err == nil {
+		t.Error("非结构体指针应返回错误")
+	}
+
+	// 后面的字段冲突时不应注册任何 flag
+	partial := flag.NewFlagSet("app", flag.ContinueOnError)
+	partial.String("max-conns", "", "")
+	if _, err := RegisterFlags(partial, &Config{}); err == nil {
+		t.Error("flag 冲突时应返回错误")
+	}
+	if partial.Lookup("server-host") != nil {
+		t.Error("失败时不应注册任何 flag")
+	}
+	var dup struct {
+		A string `flag:"name"`
+		B string `flag:"name"`
+	}
+	if _, err := RegisterPFlags(pflag.NewFlagSet("app", pflag.ContinueOnError), &dup); err == nil {
+		t.Error("字段的 flag 名称重复时应返回错误")
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	tests := map[string]string{
+		"ReadTimeout":  "read-timeout",
+		"read_timeout": "read-timeout",
+		"DBHost":       "db-host",
+		"port":         "port",
+		"MaxConns":     "max-conns",
+	}
+	for in, want := range tests {
+		if got := kebabCase(in); got != want {
+			t.Errorf("kebabCase(%q): 期望 %q，得到 %q", in, want, got)
+		}
+	}
+}
@@ -1,21 +1,67 @@
 package gconf
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // Gconf 配置管理器，封装 viper，提供更便捷的配置管理功能
+// Gconf 的所有方法都可以在多个 goroutine 中并发调用
 type Gconf struct {
-	viper            *viper.Viper
+	viper *viper.Viper
+	// parent 和 prefix 只在 Sub 返回的视图中设置：视图的所有操作都按前缀转发给父实例
+	parent           *Gconf
+	prefix           string
+	options          *Options
 	mu               sync.RWMutex
-	onChangeHandlers []func(fsnotify.Event)
+	onChangeHandlers []ChangeHandler
+	reloader         reloader
+	queue            eventQueue
+	layers           layers
+
+	// sources 按优先级从低到高排列的配置源，创建后不再变化
+	sources []Source
+	// profiles 激活的 profile
+	profiles []string
+	// mainFile 主配置文件，files 为按合并顺序排列的所有已加载配置文件
+	mainFile string
+	files    []string
+	// fileSettings 当前生效的配置文件层（合并后的所有配置文件内容），不可修改
+	fileSettings map[string]interface{}
+	// sourceEntries 当前生效的各配置源的内容，按合并顺序排列，不可修改
+	sourceEntries []sourceEntry
+	// dotenv 当前生效的 .env 变量，不可修改
+	dotenv map[string]dotEnvVar
+
+	validators          []Validator
+	reloadErrorHandlers []func(error)
+	// lastHandlerErr 最近一次配置变化通知中回调的执行结果
+	lastHandlerErr error
+	bindings       []*Binding
+	subscriptions  []*subscription
+
+	// generation 配置代数，每次重新加载或修改配置后递增
+	generation uint64
+	snapshot   *Snapshot
+	// history 保留的配置版本，按从旧到新排列
+	history []Revision
+
+	watcher *fileWatcher
+	closed  bool
+	done    chan struct{}
 }
 
 // Options 配置选项
@@ -26,22 +72,78 @@ type Options struct {
 	ConfigName string
 	// 配置文件类型（yaml, json, toml, properties, hcl, env, ini）
 	ConfigType string
+	// 配置源，按优先级从低到高排列；设置后 ConfigFiles、ConfigPaths、ConfigName、ConfigDir 不再生效
+	Sources []Source
+	// 按顺序深度合并的配置文件列表；设置后不再通过 ConfigPaths 和 ConfigName 查找配置文件
+	ConfigFiles []ConfigFile
+	// 激活的 profile，每个配置文件之后合并对应的覆盖文件（例如 config.prod.yaml）
+	Profiles []string
+	// 指定激活 profile 的环境变量（例如 APP_PROFILE），不为空时代替 Profiles
+	ProfileEnv string
+	// 配置片段目录（例如 conf.d），其中匹配 ConfigDirGlob 的文件按字典序合并到主配置之后
+	ConfigDir string
+	// 配置片段文件的匹配规则，默认为 "*"
+	ConfigDirGlob string
 	// 是否自动监听配置文件变化
 	WatchConfig bool
+	// 监听配置文件变化的方式，默认使用 fsnotify
+	WatchMode WatchMode
+	// 轮询配置文件的间隔（0 表示使用 DefaultPollInterval）
+	PollInterval time.Duration
+	// 收到这些信号时重新加载配置（例如 syscall.SIGHUP）
+	ReloadSignals []os.Signal
+	// 配置文件变化的防抖时间，窗口期内的多次变化合并为一次重新加载（0 表示不合并）
+	ReloadDebounce time.Duration
 	// 是否自动读取环境变量
 	AutomaticEnv bool
 	// 环境变量前缀
 	EnvPrefix string
 	// 环境变量键的替换规则（例如将 . 替换为 _）
 	EnvKeyReplacer *strings.Replacer
+	// 作为环境变量层读取的 .env 文件，后面的文件覆盖前面的文件
+	DotEnvFiles []string
+	// .env 文件中的变量是否覆盖同名的真实环境变量
+	DotEnvOverride bool
+	// 作为配置层的标准库 flag.FlagSet
+	FlagSets []*flag.FlagSet
+	// 作为配置层的 pflag.FlagSet
+	PFlagSets []*pflag.FlagSet
+	// flag 名称到配置键的替换规则（例如将 - 替换为 .）
+	FlagKeyReplacer *strings.Replacer
+	// flag 名称到配置键的映射，优先于 FlagKeyReplacer
+	FlagKeys map[string]string
+	// 是否展开配置值中的 ${VAR}、${key} 引用
+	Interpolate bool
 	// 配置变化回调函数
 	OnConfigChange func(fsnotify.Event)
+	// 配置变化回调函数，携带变化前后的配置项差异
+	OnChange func(ChangeEvent)
+	// 配置校验函数，重新加载的配置通过所有校验后才会生效
+	Validators []Validator
+	// 重新加载配置失败时的回调函数
+	OnReloadError func(error)
+	// 配置变化回调的执行方式，默认并发执行
+	DispatchMode DispatchMode
+	// 并发执行回调时的最大并发数（0 表示不限制）
+	MaxConcurrency int
+	// 单个回调的超时时间（0 表示不限制）
+	HandlerTimeout time.Duration
+	// 回调返回错误、panic 或超时时的回调函数，参数为 *DispatchError
+	OnHandlerError func(error)
+	// 保留的配置版本数量（默认 0，表示不记录历史）
+	HistorySize int
 	// 是否启用调试日志
 	Debug bool
 }
 
 // New 创建一个新的配置管理器实例
+// 不再使用时应调用 Close 停止监听并释放资源
 func New(opts ...Option) (*Gconf, error) {
+	return NewWithContext(context.Background(), opts...)
+}
+
+// NewWithContext 创建一个新的配置管理器实例，ctx 结束时自动调用 Close
+func NewWithContext(ctx context.Context, opts ...Option) (*Gconf, error) {
 	options := &Options{
 		ConfigPaths: []string{".", "./config"},
 		ConfigName:  "config",
@@ -57,68 +159,74 @@ func New(opts ...Option) (*Gconf, error) {
 
 	g := &Gconf{
 		viper:            viper.New(),
-		onChangeHandlers: make([]func(fsnotify.Event), 0),
+		options:          options,
+		onChangeHandlers: make([]ChangeHandler, 0),
+		validators:       append([]Validator(nil), options.Validators...),
+		done:             make(chan struct{}),
 	}
 
-	// 设置配置文件路径
-	for _, path := range options.ConfigPaths {
-		g.viper.AddConfigPath(path)
+	// 选项中的回调最先执行
+	if options.OnConfigChange != nil {
+		g.OnConfigChange(options.OnConfigChange)
 	}
-
-	// 设置配置文件名
-	g.viper.SetConfigName(options.ConfigName)
-
-	// 设置配置文件类型
-	if options.ConfigType != "" {
-		g.viper.SetConfigType(options.ConfigType)
+	if options.OnChange != nil {
+		g.OnChange(options.OnChange)
 	}
 
-	// 设置环境变量
-	if options.AutomaticEnv {
-		g.viper.AutomaticEnv()
-		if options.EnvPrefix != "" {
-			g.viper.SetEnvPrefix(options.EnvPrefix)
-		}
-		if options.EnvKeyReplacer != nil {
-			g.viper.SetEnvKeyReplacer(options.EnvKeyReplacer)
-		}
+	// 设置配置文件查找规则和环境变量规则
+	configureViper(g.viper, options)
+	g.profiles = activeProfiles(options)
+	g.sources = options.Sources
+	if len(g.sources) == 0 {
+		g.sources = g.defaultSources()
 	}
 
 	// 读取配置文件
-	if err := g.viper.ReadInConfig(); err != nil {
+	layer, err := g.loadFiles()
+	if err != nil {
 		if options.Debug {
 			log.Printf("[gconf] 读取配置文件失败: %v", err)
 		}
-		// 配置文件不存在不算错误，可以使用默认值或环境变量
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
+		return nil, err
+	}
+	// 配置文件不存在不算错误，可以使用默认值或环境变量
+	if options.Debug {
+		if len(layer.files) == 0 {
+			log.Printf("[gconf] 未找到配置文件 %s，搜索路径: %v", options.ConfigName, options.ConfigPaths)
+		} else {
+			log.Printf("[gconf] 成功加载配置文件: %s", strings.Join(layer.files, ", "))
+		}
+		if len(g.profiles) > 0 {
+			log.Printf("[gconf] 激活的 profile: %s", strings.Join(g.profiles, ", "))
 		}
-	} else if options.Debug {
-		log.Printf("[gconf] 成功加载配置文件: %s", g.viper.ConfigFileUsed())
 	}
+	g.applyFiles(layer)
+	settings, err := g.expandedSettings(g.viper, g.dotenv)
+	if err != nil {
+		return nil, err
+	}
+	g.reloader.hash = layer.hash
+	g.recordRevision(SourceLoad, strings.Join(layer.files, ", "), diffSettings(nil, settings))
 
 	// 设置配置监听
 	if options.WatchConfig {
-		g.viper.WatchConfig()
-		g.viper.OnConfigChange(func(e fsnotify.Event) {
-			if options.Debug {
-				log.Printf("[gconf] 配置文件变化: %s, 操作: %s", e.Name, e.Op)
-			}
-
-			// 执行自定义回调
-			if options.OnConfigChange != nil {
-				options.OnConfigChange(e)
-			}
-
-			// 执行注册的所有回调
-			g.mu.RLock()
-			handlers := g.onChangeHandlers
-			g.mu.RUnlock()
+		if err := g.startWatch(); err != nil {
+			log.Printf("[gconf] 监听配置文件失败: %v", err)
+		}
+		g.watchSources()
+	}
+	if len(options.ReloadSignals) > 0 {
+		g.watchSignals()
+	}
 
-			for _, handler := range handlers {
-				go handler(e)
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = g.Close()
+			case <-g.done:
 			}
-		})
+		}()
 	}
 
 	return g, nil
@@ -148,6 +256,46 @@ func WithConfigType(configType string) Option {
 	}
 }
 
+// ConfigFile 配置文件列表中的一个文件
+type ConfigFile struct {
+	// 配置文件路径，支持环境变量
+	Path string
+	// 是否必须存在，不存在时 New 和重新加载返回错误；否则跳过
+	Required bool
+}
+
+// WithConfigFiles 按顺序加载并深度合并多个配置文件，后面的文件覆盖前面的文件
+// 文件不存在时跳过，必须存在的文件使用 WithRequiredConfigFiles；两者可以混用，按调用顺序合并
+func WithConfigFiles(paths ...string) Option {
+	return func(o *Options) {
+		for _, path := range paths {
+			o.ConfigFiles = append(o.ConfigFiles, ConfigFile{Path: path})
+		}
+	}
+}
+
+// WithRequiredConfigFiles 与 WithConfigFiles 相同，但文件必须存在
+func WithRequiredConfigFiles(paths ...string) Option {
+	return func(o *Options) {
+		for _, path := range paths {
+			o.ConfigFiles = append(o.ConfigFiles, ConfigFile{Path: path, Required: true})
+		}
+	}
+}
+
+// WithConfigDir 设置配置片段目录（例如 conf.d）
+// 目录中匹配 glob 的文件（例如 "*.yaml"）按字典序深度合并到主配置之后，后面的片段覆盖前面的值；
+// 启用监听时，片段的新增、修改和删除都会触发重新加载
+func WithConfigDir(dir, glob string) Option {
+	return func(o *Options) {
+		if glob == "" {
+			glob = "*"
+		}
+		o.ConfigDir = dir
+		o.ConfigDirGlob = glob
+	}
+}
+
 // WithWatchConfig 启用配置文件监听
 func WithWatchConfig(watch bool) Option {
 	return func(o *Options) {
@@ -155,6 +303,14 @@ func WithWatchConfig(watch bool) Option {
 	}
 }
 
+// WithReloadDebounce 设置配置文件变化的防抖时间
+// 编辑器或配置管理工具保存文件时往往分多步完成，窗口期内的多次变化只触发一次重新加载
+func WithReloadDebounce(d time.Duration) Option {
+	return func(o *Options) {
+		o.ReloadDebounce = d
+	}
+}
+
 // WithAutomaticEnv 启用自动读取环境变量
 func WithAutomaticEnv(auto bool) Option {
 	return func(o *Options) {
@@ -183,6 +339,13 @@ func WithOnConfigChange(fn func(fsnotify.Event)) Option {
 	}
 }
 
+// WithOnChange 设置配置变化回调，回调参数包含变化前后的配置项差异
+func WithOnChange(fn func(ChangeEvent)) Option {
+	return func(o *Options) {
+		o.OnChange = fn
+	}
+}
+
 // WithDebug 启用调试模式
 func WithDebug(debug bool) Option {
 	return func(o *Options) {
@@ -192,195 +355,498 @@ func WithDebug(debug bool) Option {
 
 // OnConfigChange 注册配置变化回调函数
 func (g *Gconf) OnConfigChange(fn func(fsnotify.Event)) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	g.onChangeHandlers = append(g.onChangeHandlers, fn)
+	g.OnChange(func(e ChangeEvent) {
+		fn(e.Event)
+	})
+}
+
+// OnChange 注册配置变化回调函数，回调参数包含变化前后的配置项差异
+func (g *Gconf) OnChange(fn func(ChangeEvent)) {
+	g.OnChangeE(func(e ChangeEvent) error {
+		fn(e)
+		return nil
+	})
+}
+
+// OnKeyChange 注册只关注部分配置项的回调函数
+// 只有匹配 pattern 的配置项发生变化时才会触发，回调收到的事件只包含匹配的变化，
+// pattern 规则见 MatchKey，例如 "database.*"、"server.port"
+func (g *Gconf) OnKeyChange(pattern string, fn func(ChangeEvent)) {
+	g.OnChange(func(e ChangeEvent) {
+		if filtered := e.Filter(pattern); filtered.HasChanges() {
+			fn(filtered)
+		}
+	})
 }
 
 // Get 获取配置值
 func (g *Gconf) Get(key string) interface{} {
-	return g.viper.Get(key)
+	if g.parent != nil {
+		return g.parent.Get(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.get(key)
 }
 
 // GetString 获取字符串类型配置
 func (g *Gconf) GetString(key string) string {
-	return g.viper.GetString(key)
+	if g.parent != nil {
+		return g.parent.GetString(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToString(g.get(key))
 }
 
 // GetBool 获取布尔类型配置
 func (g *Gconf) GetBool(key string) bool {
-	return g.viper.GetBool(key)
+	if g.parent != nil {
+		return g.parent.GetBool(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToBool(g.get(key))
 }
 
 // GetInt 获取整数类型配置
 func (g *Gconf) GetInt(key string) int {
-	return g.viper.GetInt(key)
+	if g.parent != nil {
+		return g.parent.GetInt(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToInt(g.get(key))
 }
 
 // GetInt32 获取 int32 类型配置
 func (g *Gconf) GetInt32(key string) int32 {
-	return g.viper.GetInt32(key)
+	if g.parent != nil {
+		return g.parent.GetInt32(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToInt32(g.get(key))
 }
 
 // GetInt64 获取 int64 类型配置
 func (g *Gconf) GetInt64(key string) int64 {
-	return g.viper.GetInt64(key)
+	if g.parent != nil {
+		return g.parent.GetInt64(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToInt64(g.get(key))
 }
 
 // GetUint 获取无符号整数类型配置
 func (g *Gconf) GetUint(key string) uint {
-	return g.viper.GetUint(key)
+	if g.parent != nil {
+		return g.parent.GetUint(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToUint(g.get(key))
 }
 
 // GetUint32 获取 uint32 类型配置
 func (g *Gconf) GetUint32(key string) uint32 {
-	return g.viper.GetUint32(key)
+	if g.parent != nil {
+		return g.parent.GetUint32(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToUint32(g.get(key))
 }
 
 // GetUint64 获取 uint64 类型配置
 func (g *Gconf) GetUint64(key string) uint64 {
-	return g.viper.GetUint64(key)
+	if g.parent != nil {
+		return g.parent.GetUint64(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToUint64(g.get(key))
 }
 
 // GetFloat64 获取浮点数类型配置
 func (g *Gconf) GetFloat64(key string) float64 {
-	return g.viper.GetFloat64(key)
+	if g.parent != nil {
+		return g.parent.GetFloat64(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToFloat64(g.get(key))
 }
 
 // GetTime 获取时间类型配置
 func (g *Gconf) GetTime(key string) time.Time {
-	return g.viper.GetTime(key)
+	if g.parent != nil {
+		return g.parent.GetTime(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToTime(g.get(key))
 }
 
 // GetDuration 获取时间间隔类型配置
 func (g *Gconf) GetDuration(key string) time.Duration {
-	return g.viper.GetDuration(key)
+	if g.parent != nil {
+		return g.parent.GetDuration(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToDuration(g.get(key))
 }
 
 // GetStringSlice 获取字符串切片类型配置
 func (g *Gconf) GetStringSlice(key string) []string {
-	return g.viper.GetStringSlice(key)
+	if g.parent != nil {
+		return g.parent.GetStringSlice(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToStringSlice(g.get(key))
 }
 
 // GetStringMap 获取字符串映射类型配置
 func (g *Gconf) GetStringMap(key string) map[string]interface{} {
-	return g.viper.GetStringMap(key)
+	if g.parent != nil {
+		return g.parent.GetStringMap(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToStringMap(g.get(key))
 }
 
 // GetStringMapString 获取字符串到字符串映射类型配置
 func (g *Gconf) GetStringMapString(key string) map[string]string {
-	return g.viper.GetStringMapString(key)
+	if g.parent != nil {
+		return g.parent.GetStringMapString(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToStringMapString(g.get(key))
 }
 
 // GetStringMapStringSlice 获取字符串到字符串切片映射类型配置
 func (g *Gconf) GetStringMapStringSlice(key string) map[string][]string {
-	return g.viper.GetStringMapStringSlice(key)
+	if g.parent != nil {
+		return g.parent.GetStringMapStringSlice(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return cast.ToStringMapStringSlice(g.get(key))
 }
 
 // GetSizeInBytes 获取字节大小类型配置（支持 KB, MB, GB 等）
 func (g *Gconf) GetSizeInBytes(key string) uint {
-	return g.viper.GetSizeInBytes(key)
+	if g.parent != nil {
+		return g.parent.GetSizeInBytes(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if !g.options.Interpolate {
+		return g.viper.GetSizeInBytes(key)
+	}
+	// viper 没有导出字节大小的解析函数，用临时实例解析展开后的值
+	v := viper.New()
+	v.Set("size", g.get(key))
+	return v.GetSizeInBytes("size")
 }
 
 // Set 设置配置值
 func (g *Gconf) Set(key string, value interface{}) {
+	if g.parent != nil {
+		g.parent.Set(g.fullKey(key), value)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var oldSettings map[string]interface{}
+	if g.historyEnabled() {
+		oldSettings = g.allSettings()
+	}
+	g.layers.overrides = recordSetting(g.layers.overrides, key, value)
 	g.viper.Set(key, value)
+	g.generation++
+	if g.historyEnabled() {
+		g.recordRevision(SourceSet, key, diffSettings(oldSettings, g.allSettings()))
+	}
 }
 
 // SetDefault 设置默认值
 func (g *Gconf) SetDefault(key string, value interface{}) {
+	if g.parent != nil {
+		g.parent.SetDefault(g.fullKey(key), value)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.layers.defaults = recordSetting(g.layers.defaults, key, value)
 	g.viper.SetDefault(key, value)
+	bindDotEnv(g.viper, g.options, g.layers.envBindings, &g.dotenv)
+	g.generation++
 }
 
 // IsSet 检查配置键是否存在
 func (g *Gconf) IsSet(key string) bool {
-	return g.viper.IsSet(key)
+	if g.parent != nil {
+		return g.parent.IsSet(g.fullKey(key))
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.viper.IsSet(key) {
+		return true
+	}
+	_, ok := g.lookupDotEnvValue(key)
+	return ok
 }
 
 // AllKeys 获取所有配置键
 func (g *Gconf) AllKeys() []string {
+	if g.parent != nil {
+		return g.viewKeys()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return g.viper.AllKeys()
 }
 
 // AllSettings 获取所有配置
 func (g *Gconf) AllSettings() map[string]interface{} {
-	return g.viper.AllSettings()
+	if g.parent != nil {
+		return g.viewSettings()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.allSettings()
 }
 
 // Unmarshal 将配置解析到结构体
 func (g *Gconf) Unmarshal(rawVal interface{}) error {
-	return g.viper.Unmarshal(rawVal)
+	if g.parent != nil {
+		return g.viewViper().Unmarshal(rawVal)
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, err := g.reader()
+	if err != nil {
+		return err
+	}
+	return v.Unmarshal(rawVal)
 }
 
 // UnmarshalKey 将指定键的配置解析到结构体
 func (g *Gconf) UnmarshalKey(key string, rawVal interface{}) error {
-	return g.viper.UnmarshalKey(key, rawVal)
+	if g.parent != nil {
+		return g.parent.UnmarshalKey(g.fullKey(key), rawVal)
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, err := g.reader()
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalKey(key, rawVal)
 }
 
 // UnmarshalExact 严格解析配置到结构体（结构体中未定义的字段会报错）
 func (g *Gconf) UnmarshalExact(rawVal interface{}) error {
-	return g.viper.UnmarshalExact(rawVal)
+	if g.parent != nil {
+		return g.viewViper().UnmarshalExact(rawVal)
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	v, err := g.reader()
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalExact(rawVal)
 }
 
+// ErrMultipleConfigFiles 配置由多个配置文件合并而成，写回其中任何一个都会把其他文件的内容合并进去
+var ErrMultipleConfigFiles = errors.New("gconf: 配置由多个配置文件合并而成，不能写回已加载的配置文件")
+
 // WriteConfig 写入配置到文件
+// 配置由多个配置文件合并而成（$include、配置目录、WithConfigFiles 或 profile）时返回 ErrMultipleConfigFiles，
+// 此时可以用 WriteConfigAs 导出到其他文件
 func (g *Gconf) WriteConfig() error {
+	if g.parent != nil {
+		return g.parent.WriteConfig()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if len(g.files) > 1 {
+		return ErrMultipleConfigFiles
+	}
 	return g.viper.WriteConfig()
 }
 
 // SafeWriteConfig 安全写入配置（文件存在时不覆盖）
 func (g *Gconf) SafeWriteConfig() error {
+	if g.parent != nil {
+		return g.parent.SafeWriteConfig()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
 	return g.viper.SafeWriteConfig()
 }
 
 // WriteConfigAs 写入配置到指定文件
+// 配置由多个配置文件合并而成且 filename 是其中之一时返回 ErrMultipleConfigFiles
 func (g *Gconf) WriteConfigAs(filename string) error {
+	if g.parent != nil {
+		return g.parent.WriteConfigAs(filename)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if len(g.files) > 1 && stringInSlice(absPath(filename), g.files) {
+		return ErrMultipleConfigFiles
+	}
 	return g.viper.WriteConfigAs(filename)
 }
 
 // SafeWriteConfigAs 安全写入配置到指定文件（文件存在时不覆盖）
 func (g *Gconf) SafeWriteConfigAs(filename string) error {
+	if g.parent != nil {
+		return g.parent.SafeWriteConfigAs(filename)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
 	return g.viper.SafeWriteConfigAs(filename)
 }
 
-// ReadInConfig 重新读取配置文件
+// ReadInConfig 重新读取所有配置源（包括配置目录、profile、$include 和 .env 文件）
+// 不会执行校验或通知回调；需要这些行为时使用 Reload
 func (g *Gconf) ReadInConfig() error {
-	return g.viper.ReadInConfig()
+	if g.parent != nil {
+		return g.parent.ReadInConfig()
+	}
+	r := &g.reloader
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+
+	if g.isClosed() {
+		return ErrClosed
+	}
+	layer, err := g.loadFiles()
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	var oldSettings map[string]interface{}
+	if g.historyEnabled() {
+		oldSettings = g.allSettings()
+	}
+	g.applyFiles(layer)
+	g.generation++
+	if g.historyEnabled() {
+		g.recordRevision(SourceLoad, strings.Join(layer.files, ", "), diffSettings(oldSettings, g.allSettings()))
+	}
+	g.mu.Unlock()
+	r.hash, r.err = layer.hash, nil
+
+	g.refreshBindings()
+	return nil
 }
 
-// MergeInConfig 合并配置文件
+// MergeInConfig 与 ReadInConfig 相同：配置文件层总是由所有配置源按顺序合并而成
+//
+// Deprecated: 使用 ReadInConfig 或 Reload
 func (g *Gconf) MergeInConfig() error {
-	return g.viper.MergeInConfig()
+	return g.ReadInConfig()
+}
+
+// ConfigFilesUsed 获取按合并顺序排列的所有已加载配置文件
+func (g *Gconf) ConfigFilesUsed() []string {
+	if g.parent != nil {
+		return g.parent.ConfigFilesUsed()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]string(nil), g.files...)
 }
 
 // ConfigFileUsed 获取当前使用的配置文件路径
 func (g *Gconf) ConfigFileUsed() string {
+	if g.parent != nil {
+		return g.parent.ConfigFileUsed()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return g.viper.ConfigFileUsed()
 }
 
 // BindEnv 绑定环境变量到配置键
 func (g *Gconf) BindEnv(keys ...string) error {
-	return g.viper.BindEnv(keys...)
+	if g.parent != nil && len(keys) > 0 {
+		return g.parent.BindEnv(append([]string{g.fullKey(keys[0])}, keys[1:]...)...)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.viper.BindEnv(keys...); err != nil {
+		return err
+	}
+	g.layers.envBindings = append(g.layers.envBindings, append([]string(nil), keys...))
+	bindDotEnv(g.viper, g.options, g.layers.envBindings, &g.dotenv)
+	g.generation++
+	return nil
 }
 
 // RegisterAlias 注册配置键别名
 func (g *Gconf) RegisterAlias(alias string, key string) {
+	if g.parent != nil {
+		g.parent.RegisterAlias(g.fullKey(alias), g.fullKey(key))
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.layers.aliases = append(g.layers.aliases, setting{key: alias, value: key})
 	g.viper.RegisterAlias(alias, key)
+	g.generation++
 }
 
-// Sub 获取子配置树
+// Sub 获取子配置树的视图，子配置树不存在或 key 对应的不是配置树（例如普通的配置值）时返回 nil
+// 视图不复制配置：读取时按前缀访问父实例的所有配置层（配置文件、环境变量、默认值、别名），
+// 始终反映父实例的最新配置；Set、SetDefault 等写操作写入父实例；
+// 在视图上注册的回调只在前缀下的配置项变化时触发，事件中的配置键不包含前缀
 func (g *Gconf) Sub(key string) *Gconf {
-	subViper := g.viper.Sub(key)
-	if subViper == nil {
+	if g.parent != nil {
+		return g.parent.Sub(g.fullKey(key))
+	}
+	value := g.Get(key)
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Map {
 		return nil
 	}
 	return &Gconf{
-		viper:            subViper,
-		onChangeHandlers: make([]func(fsnotify.Event), 0),
+		parent:  g,
+		prefix:  strings.ToLower(key),
+		options: g.options,
+		done:    g.done,
 	}
 }
 
 // GetViper 获取底层的 viper 实例（用于高级操作）
+// 直接操作 viper 实例不受 Gconf 的并发保护，也不会触发配置变化通知；Revert 之后底层实例会被替换。
+// 子配置视图返回父实例的 viper 实例
 func (g *Gconf) GetViper() *viper.Viper {
+	if g.parent != nil {
+		return g.parent.GetViper()
+	}
 	return g.viper
 }
 
@@ -1,6 +1,7 @@
 package gconf
 
 import (
+	"context"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
@@ -25,25 +26,36 @@ func Init(opts ...Option) error {
 }
 
 // InitWithConfig 使用自定义配置初始化全局实例
-// 此方法允许在初始化后重新设置全局实例（慎用）
+// 此方法允许在初始化后重新设置全局实例（慎用），替换成功后关闭原实例；创建失败时保留原实例
 func InitWithConfig(opts ...Option) error {
 	defaultInstanceMu.Lock()
-	defer defaultInstanceMu.Unlock()
-
 	instance, err := New(opts...)
 	if err != nil {
+		defaultInstanceMu.Unlock()
 		return err
 	}
+	old := defaultInstance
 	defaultInstance = instance
+	defaultInstanceMu.Unlock()
+
+	// 原实例可能已被调用方关闭，忽略关闭的结果
+	if old != nil {
+		_ = old.Close()
+	}
 	return nil
 }
 
 // GetInstance 获取全局配置实例
 // 如果未初始化，将使用默认配置自动初始化
 func GetInstance() *Gconf {
-	if defaultInstance == nil {
-		_ = Init() // 使用默认配置初始化
+	defaultInstanceMu.RLock()
+	instance := defaultInstance
+	defaultInstanceMu.RUnlock()
+	if instance != nil {
+		return instance
 	}
+
+	_ = Init() // 使用默认配置初始化
 	defaultInstanceMu.RLock()
 	defer defaultInstanceMu.RUnlock()
 	return defaultInstance
@@ -151,6 +163,11 @@ func UnmarshalKey(key string, rawVal interface{}) error {
 	return GetInstance().UnmarshalKey(key, rawVal)
 }
 
+// GetSnapshot 获取当前配置的只读快照
+func GetSnapshot() *Snapshot {
+	return GetInstance().Snapshot()
+}
+
 // WriteConfig 写入配置到文件
 func WriteConfig() error {
 	return GetInstance().WriteConfig()
@@ -161,11 +178,71 @@ func ConfigFileUsed() string {
 	return GetInstance().ConfigFileUsed()
 }
 
+// ConfigFilesUsed 获取全局配置按合并顺序排列的所有已加载配置文件
+func ConfigFilesUsed() []string {
+	return GetInstance().ConfigFilesUsed()
+}
+
 // OnConfigChange 注册配置变化回调函数
 func OnConfigChange(fn func(fsnotify.Event)) {
 	GetInstance().OnConfigChange(fn)
 }
 
+// OnChange 注册配置变化回调函数，回调参数包含变化前后的配置项差异
+func OnChange(fn func(ChangeEvent)) {
+	GetInstance().OnChange(fn)
+}
+
+// OnKeyChange 注册只关注部分配置项的回调函数
+func OnKeyChange(pattern string, fn func(ChangeEvent)) {
+	GetInstance().OnKeyChange(pattern, fn)
+}
+
+// Explain 说明全局配置中配置项的来源
+func Explain(key string) Explanation {
+	return GetInstance().Explain(key)
+}
+
+// Profiles 返回全局配置激活的 profile
+func Profiles() []string {
+	return GetInstance().Profiles()
+}
+
+// Layers 返回全局配置当前生效的配置层名称，按优先级从高到低排列
+func Layers() []string {
+	return GetInstance().Layers()
+}
+
+// Watch 返回接收全局配置变化事件的通道，ctx 结束时通道被关闭
+func Watch(ctx context.Context, keys ...string) <-chan ChangeEvent {
+	return GetInstance().Watch(ctx, keys...)
+}
+
+// Bind 将全局配置绑定到 ptr 指向的结构体类型
+func Bind(ptr interface{}) (*Binding, error) {
+	return GetInstance().Bind(ptr)
+}
+
+// History 返回全局配置保留的配置版本，按从旧到新排列
+func History() []Revision {
+	return GetInstance().History()
+}
+
+// Revert 将全局配置恢复到指定版本
+func Revert(id uint64) error {
+	return GetInstance().Revert(id)
+}
+
+// Reload 立即重新加载全局配置并通知回调，返回配置是否发生变化
+func Reload(ctx context.Context) (bool, error) {
+	return GetInstance().Reload(ctx)
+}
+
+// Close 关闭全局配置实例，停止监听并释放资源
+func Close() error {
+	return GetInstance().Close()
+}
+
 // Debug 打印所有配置信息（用于调试）
 func Debug() {
 	GetInstance().Debug()
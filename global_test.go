@@ -225,4 +225,17 @@ func TestInitWithConfig(t *testing.T) {
 	if instance == nil {
 		t.Fatal("重新初始化后实例不应为 nil")
 	}
+
+	// 替换后关闭原实例
+	if err := InitWithConfig(WithConfigName("test3")); err != nil {
+		t.Logf("重新初始化时出错: %v", err)
+	}
+	select {
+	case <-instance.Done():
+	default:
+		t.Error("替换后原实例应被关闭")
+	}
+	if GetInstance() == instance {
+		t.Error("全局实例应被替换")
+	}
 }
@@ -4,5 +4,7 @@ go 1.14
 
 require (
 	github.com/fsnotify/fsnotify v1.4.9
+	github.com/spf13/cast v1.3.0
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.7.1
 )
@@ -0,0 +1,155 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultHistorySize 建议保留的配置版本数量，可用于 WithHistorySize
+const DefaultHistorySize = 10
+
+// ErrRevisionNotFound 指定的配置版本不存在或已被淘汰
+var ErrRevisionNotFound = errors.New("gconf: 配置版本不存在")
+
+// 配置版本的来源
+const (
+	SourceLoad   = "load"
+	SourceReload = "reload"
+	SourceSet    = "set"
+	SourceRevert = "revert"
+)
+
+// Revision 配置的一个历史版本
+type Revision struct {
+	// 版本号，即产生该版本时的配置代数
+	ID uint64
+	// 产生该版本的时间
+	Time time.Time
+	// 产生该版本的操作：SourceLoad、SourceReload、SourceSet 或 SourceRevert
+	Source string
+	// 操作的详细信息，例如重新加载的文件或 Set 的键
+	Detail string
+	// 与上一个版本相比的配置项差异
+	Changes []KeyChange
+
+	// 恢复该版本所需的配置文件层和 Set 值
+	fileSettings  map[string]interface{}
+	sourceEntries []sourceEntry
+	overrides     []setting
+}
+
+// WithHistorySize 设置保留的配置版本数量，默认为 0，即不记录历史
+// 记录历史时每次 Set 都会比较前后的全部配置，频繁调用 Set 且配置较多时需要权衡开销
+func WithHistorySize(n int) Option {
+	return func(o *Options) {
+		o.HistorySize = n
+	}
+}
+
+// History 返回保留的配置版本，按从旧到新排列，未通过 WithHistorySize 启用时为空
+func (g *Gconf) History() []Revision {
+	if g.parent != nil {
+		return g.parent.History()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	revisions := make([]Revision, len(g.history))
+	copy(revisions, g.history)
+	return revisions
+}
+
+// Revert 将配置恢复到指定版本：恢复该版本的配置文件内容和 Set 值，并像重新加载一样通知回调
+// 恢复的配置文件内容只在内存中生效，配置文件之后再发生变化时会重新加载文件；
+// 恢复操作本身也会记录为一个新版本
+func (g *Gconf) Revert(id uint64) error {
+	if g.parent != nil {
+		return g.parent.Revert(id)
+	}
+	ev, err := g.revert(id)
+	if err != nil {
+		return err
+	}
+	_ = g.notify(*ev)
+	return nil
+}
+
+// revert 恢复到指定版本并返回需要通知回调的事件，与重新加载串行执行，但不包括回调通知
+func (g *Gconf) revert(id uint64) (*ChangeEvent, error) {
+	r := &g.reloader
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil, ErrClosed
+	}
+	var target *Revision
+	for i := range g.history {
+		if g.history[i].ID == id {
+			target = &g.history[i]
+		}
+	}
+	if target == nil {
+		g.mu.Unlock()
+		return nil, ErrRevisionNotFound
+	}
+
+	l := g.layers
+	l.overrides = append([]setting(nil), target.overrides...)
+	layer := &fileLayer{settings: target.fileSettings, mainFile: g.mainFile, dotenv: g.dotenv}
+	v, err := newViper(g.options, layer, &l)
+	if err != nil {
+		g.mu.Unlock()
+		return nil, err
+	}
+
+	oldSettings := g.allSettings()
+	g.viper = v
+	g.layers = l
+	// 新实例的 .env 变量绑定指向临时的配置文件层，改为指向当前实例
+	bindDotEnv(g.viper, g.options, g.layers.envBindings, &g.dotenv)
+	g.fileSettings = target.fileSettings
+	g.sourceEntries = target.sourceEntries
+	newSettings := g.allSettings()
+	g.generation++
+	changes := diffSettings(oldSettings, newSettings)
+	g.recordRevision(SourceRevert, fmt.Sprintf("%d", id), changes)
+	mainFile := g.mainFile
+	g.mu.Unlock()
+
+	g.refreshBindings()
+	return &ChangeEvent{
+		Event:   fsnotify.Event{Name: mainFile},
+		Changes: changes,
+	}, nil
+}
+
+// recordRevision 记录当前配置为一个新版本，调用方需持有写锁
+func (g *Gconf) recordRevision(source, detail string, changes []KeyChange) {
+	size := g.options.HistorySize
+	if size <= 0 {
+		return
+	}
+	g.history = append(g.history, Revision{
+		ID:            g.generation,
+		Time:          time.Now(),
+		Source:        source,
+		Detail:        detail,
+		Changes:       changes,
+		fileSettings:  g.fileSettings,
+		sourceEntries: g.sourceEntries,
+		overrides:     append([]setting(nil), g.layers.overrides...),
+	})
+	if n := len(g.history) - size; n > 0 {
+		g.history = append([]Revision(nil), g.history[n:]...)
+	}
+}
+
+// historyEnabled 判断是否记录历史版本
+func (g *Gconf) historyEnabled() bool {
+	return g.options.HistorySize > 0
+}
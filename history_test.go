@@ -0,0 +1,96 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(WithConfigPaths(dir), WithHistorySize(3))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	history := conf.History()
+	if len(history) != 1 || history[0].Source != SourceLoad {
+		t.Fatalf("应记录初始版本: %+v", history)
+	}
+	initial := history[0].ID
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	conf.Set("server.host", "example.com")
+
+	history = conf.History()
+	if len(history) != 3 {
+		t.Fatalf("期望 3 个版本，得到 %d", len(history))
+	}
+	if r := history[1]; r.Source != SourceReload || r.Detail != file {
+		t.Errorf("第 2 个版本应来自重新加载: %+v", r)
+	} else if c, ok := (ChangeEvent{Changes: r.Changes}).Change("server.port"); !ok || c.OldValue != 8080 || c.NewValue != 9090 {
+		t.Errorf("重新加载版本的差异不正确: %+v", r.Changes)
+	}
+	if r := history[2]; r.Source != SourceSet || r.Detail != "server.host" || len(r.Changes) != 1 {
+		t.Errorf("第 3 个版本应来自 Set: %+v", r)
+	}
+
+	events := make(chan ChangeEvent, 1)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+	if err := conf.Revert(initial); err != nil {
+		t.Fatalf("恢复版本失败: %v", err)
+	}
+	if v := conf.GetInt("server.port"); v != 8080 {
+		t.Errorf("恢复后期望 8080，得到 %d", v)
+	}
+	if conf.IsSet("server.host") {
+		t.Error("恢复后不应保留之后 Set 的值")
+	}
+	if e := <-events; len(e.Changes) != 2 {
+		t.Errorf("恢复应通知回调: %+v", e.Changes)
+	}
+
+	history = conf.History()
+	if len(history) != 3 || history[2].Source != SourceRevert {
+		t.Errorf("恢复应记录为新版本，并淘汰最旧的版本: %+v", history)
+	}
+	if err := conf.Revert(initial); err != ErrRevisionNotFound {
+		t.Errorf("已淘汰的版本应返回 ErrRevisionNotFound，得到 %v", err)
+	}
+
+	// 配置文件再次变化时重新加载文件
+	writeFile(t, file, "server:\n  port: 10000\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if v := conf.GetInt("server.port"); v != 10000 {
+		t.Errorf("期望 10000，得到 %d", v)
+	}
+
+	// 默认不记录历史
+	plain, err := New(WithConfigPaths(dir))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer plain.Close()
+	plain.Set("server.host", "example.com")
+	if history := plain.History(); len(history) != 0 {
+		t.Errorf("默认不应记录历史: %+v", history)
+	}
+}
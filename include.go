@@ -0,0 +1,116 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeKey 配置文件中引用其他配置文件的保留键，值为一个路径或路径列表，例如：
+//
+//	$include: [common/logging.yaml, db.yaml]
+//
+// 相对路径相对于当前配置文件所在目录，路径中可以使用环境变量（$HOME、${APP_ROOT}）；
+// 被引用的文件按列表顺序先合并，当前配置文件中的配置覆盖被引用的配置。
+// 被引用的文件必须存在，它们也可以继续引用其他文件
+const IncludeKey = "$include"
+
+// ErrIncludeCycle 配置文件循环引用
+var ErrIncludeCycle = errors.New("配置文件循环引用")
+
+// IncludeError 加载被引用的配置文件失败的错误
+type IncludeError struct {
+	// 引用链，从配置源中的配置文件开始，到失败的配置文件结束
+	Chain []string
+	// 失败原因
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("加载配置文件 %s 失败: %v", strings.Join(e.Chain, " -> "), e.Err)
+}
+
+// Unwrap 返回失败原因
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// loadIncludes 解析配置文件并递归加载其中引用的配置文件
+// fn 按合并顺序（被引用的文件在前）对每个文件调用一次，传入的配置树已去掉 IncludeKey；
+// chain 为引用当前文件的文件，用于检测循环引用
+func loadIncludes(file string, data []byte, configType string, chain []string,
+	fn func(file string, data []byte, settings map[string]interface{})) error {
+	chain = append(chain[:len(chain):len(chain)], file)
+
+	settings, err := parseConfig(data, fileType(file, configType))
+	if err != nil {
+		err = fmt.Errorf("解析配置文件 %s 失败: %w", file, err)
+		if len(chain) > 1 {
+			return &IncludeError{Chain: chain, Err: err}
+		}
+		return err
+	}
+
+	includes, err := includePaths(settings, file)
+	if err != nil {
+		return &IncludeError{Chain: chain, Err: err}
+	}
+	for _, inc := range includes {
+		if stringInSlice(inc, chain) {
+			return &IncludeError{Chain: append(chain, inc), Err: ErrIncludeCycle}
+		}
+		incData, err := ioutil.ReadFile(inc)
+		if err != nil {
+			return &IncludeError{Chain: append(chain, inc), Err: err}
+		}
+		if err := loadIncludes(inc, incData, configType, chain, fn); err != nil {
+			return err
+		}
+	}
+	fn(file, data, settings)
+	return nil
+}
+
+// includePaths 取出并删除配置树中的 IncludeKey，返回被引用文件的绝对路径
+func includePaths(settings map[string]interface{}, file string) ([]string, error) {
+	value, ok := settings[IncludeKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(settings, IncludeKey)
+
+	var paths []string
+	switch v := value.(type) {
+	case string:
+		paths = []string{v}
+	case []interface{}:
+		for _, p := range v {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s 只能包含路径，得到 %v", IncludeKey, p)
+			}
+			paths = append(paths, s)
+		}
+	case []string:
+		paths = v
+	default:
+		return nil, fmt.Errorf("%s 应为路径或路径列表，得到 %v", IncludeKey, value)
+	}
+
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = os.ExpandEnv(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(file), p)
+		}
+		files = append(files, filepath.Clean(p))
+	}
+	return files, nil
+}
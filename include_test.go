@@ -0,0 +1,140 @@
+package gconf
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.yaml")
+	logging := filepath.Join(dir, "common", "logging.yaml")
+	writeFile(t, file, "$include: [common/logging.yaml, db.yaml]\nserver:\n  port: 8080\ndatabase:\n  host: db.example.com\n")
+	writeFile(t, logging, "$include: level.yaml\nlog:\n  format: json\n")
+	writeFile(t, filepath.Join(dir, "common", "level.yaml"), "log:\n  level: info\n  format: text\n")
+	writeFile(t, filepath.Join(dir, "db.yaml"), "database:\n  host: localhost\n  port: 3306\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	tests := map[string]interface{}{
+		"server.port":   8080,
+		"log.level":     "info",
+		"log.format":    "json",
+		"database.host": "db.example.com",
+		"database.port": 3306,
+	}
+	for key, want := range tests {
+		if v := conf.Get(key); v != want {
+			t.Errorf("%s: 期望 %v，得到 %v", key, want, v)
+		}
+	}
+	if conf.IsSet(IncludeKey) {
+		t.Errorf("%s 不应出现在配置中", IncludeKey)
+	}
+	if files := conf.ConfigFilesUsed(); len(files) != 4 || files[len(files)-1] != file {
+		t.Errorf("已加载的配置文件应包含被引用的文件，且主配置文件最后合并: %v", files)
+	}
+	if e := conf.Explain("database.port"); e.Source == nil || e.Source.File != filepath.Join(dir, "db.yaml") {
+		t.Errorf("Explain 应指出被引用的文件: %v", e)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+	writeFile(t, logging, "$include: level.yaml\nlog:\n  format: logfmt\n")
+	select {
+	case <-events:
+		if v := conf.GetString("log.format"); v != "logfmt" {
+			t.Errorf("修改被引用的文件后期望 logfmt，得到 %s", v)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("修改被引用的文件应触发重新加载")
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.yaml")
+	b := filepath.Join(dir, "b.yaml")
+	writeFile(t, a, "$include: b.yaml\nname: a\n")
+	writeFile(t, b, "$include: [a.yaml]\nname: b\n")
+
+	_, err = New(WithConfigFiles(a))
+	var ie *IncludeError
+	if !errors.As(err, &ie) || !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("期望循环引用错误，得到 %v", err)
+	}
+	if chain := strings.Join(ie.Chain, " -> "); chain != a+" -> "+b+" -> "+a {
+		t.Errorf("错误应包含引用链，得到 %s", chain)
+	}
+
+	writeFile(t, a, "$include: missing.yaml\n")
+	_, err = New(WithConfigFiles(a))
+	if !errors.As(err, &ie) || !os.IsNotExist(errors.Unwrap(ie)) {
+		t.Errorf("被引用的文件不存在时应返回错误，得到 %v", err)
+	}
+}
+
+func TestWriteConfigMultipleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	content := "$include: db.yaml\napp: x\n"
+	writeFile(t, file, content)
+	writeFile(t, filepath.Join(dir, "db.yaml"), "db:\n  host: localhost\n")
+
+	conf, err := New(WithConfigPaths(dir))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if err := conf.WriteConfig(); err != ErrMultipleConfigFiles {
+		t.Errorf("期望 ErrMultipleConfigFiles，得到 %v", err)
+	}
+	if err := conf.WriteConfigAs(filepath.Join(dir, "db.yaml")); err != ErrMultipleConfigFiles {
+		t.Errorf("期望 ErrMultipleConfigFiles，得到 %v", err)
+	}
+	if data, _ := ioutil.ReadFile(file); string(data) != content {
+		t.Errorf("配置文件不应被修改: %s", data)
+	}
+
+	// 导出到其他文件
+	export := filepath.Join(dir, "export.yaml")
+	if err := conf.WriteConfigAs(export); err != nil {
+		t.Fatalf("导出配置失败: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(export); !strings.Contains(string(data), "localhost") {
+		t.Errorf("导出的配置应包含合并后的内容: %s", data)
+	}
+}
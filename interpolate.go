@@ -0,0 +1,301 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrUnresolvedReference 引用的环境变量和配置项都不存在，且没有默认值
+	ErrUnresolvedReference = errors.New("无法解析的引用")
+	// ErrReferenceCycle 配置项之间循环引用
+	ErrReferenceCycle = errors.New("配置项循环引用")
+)
+
+// InterpolationError 展开配置值中的引用失败的错误
+type InterpolationError struct {
+	// 展开的配置项
+	Key string
+	// 引用链，从展开的配置项开始，到失败的引用结束
+	Chain []string
+	// 失败原因
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("展开配置项 %s 失败: %s: %v", e.Key, strings.Join(e.Chain, " -> "), e.Err)
+}
+
+// Unwrap 返回失败原因
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// WithInterpolation 启用配置值中的变量展开
+// 读取方法（Get*、AllSettings、Unmarshal*、Snapshot）返回展开后的值，语法为：
+//
+//	${DB_USER}             环境变量（包括 WithDotEnv 读取的变量），不存在时查找同名的配置项
+//	${database.host}       配置项（名称中包含 . 时只查找配置项），其中的引用同样会展开
+//	${DB_PASS:-changeme}   未设置或为空时使用默认值，默认值中也可以包含引用
+//	$${literal}            转义，得到 ${literal}
+//
+// 整个配置值只有一个引用时保留被引用配置项的类型。
+// 加载和重新加载时引用无法解析或循环引用会返回 *InterpolationError（重新加载时包装在 ReloadError 中），
+// 之后 Set 引入的错误由 Unmarshal* 返回，Get* 保留无法展开的原始值
+func WithInterpolation(enable bool) Option {
+	return func(o *Options) {
+		o.Interpolate = enable
+	}
+}
+
+// interpolator 展开配置值中的引用
+type interpolator struct {
+	// 查找配置项
+	get func(key string) (interface{}, bool)
+	// 查找环境变量
+	env func(name string) (string, bool)
+}
+
+// newInterpolator 创建从 viper 实例、环境变量和 .env 变量查找引用的展开器
+func (g *Gconf) newInterpolator(v *viper.Viper, dotenv map[string]dotEnvVar) *interpolator {
+	return &interpolator{
+		get: func(key string) (interface{}, bool) {
+			if !v.IsSet(key) {
+				return nil, false
+			}
+			return v.Get(key), true
+		},
+		env: func(name string) (string, bool) {
+			return lookupEnvVar(name, dotenv, g.options.DotEnvOverride)
+		},
+	}
+}
+
+// get 获取配置值，启用变量展开时展开其中的引用，调用方需持有读锁
+func (g *Gconf) get(key string) interface{} {
+	value := g.viper.Get(key)
+	if value == nil {
+		if v, ok := g.lookupDotEnvValue(key); ok {
+			value = v
+		}
+	}
+	if !g.options.Interpolate {
+		return value
+	}
+	expanded, err := g.newInterpolator(g.viper, g.dotenv).expand(strings.ToLower(key), value, nil)
+	if err != nil && g.options.Debug {
+		log.Printf("[gconf] %v", err)
+	}
+	return expanded
+}
+
+// allSettings 获取所有配置，启用变量展开时展开其中的引用，调用方需持有读锁
+func (g *Gconf) allSettings() map[string]interface{} {
+	settings, err := g.expandedSettings(g.viper, g.dotenv)
+	if err != nil && g.options.Debug {
+		log.Printf("[gconf] %v", err)
+	}
+	return settings
+}
+
+// expandedSettings 返回 viper 实例展开后的所有配置和第一个展开错误，无法展开的值保留原样
+func (g *Gconf) expandedSettings(v *viper.Viper, dotenv map[string]dotEnvVar) (map[string]interface{}, error) {
+	settings := v.AllSettings()
+	if !g.options.Interpolate {
+		return settings, nil
+	}
+	in := g.newInterpolator(v, dotenv)
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var firstErr error
+	for _, key := range keys {
+		expanded, err := in.expand(key, settings[key], nil)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		settings[key] = expanded
+	}
+	return settings, firstErr
+}
+
+// reader 返回用于解析结构体的 viper 实例，启用变量展开时为展开后的配置构建的临时实例，调用方需持有读锁
+func (g *Gconf) reader() (*viper.Viper, error) {
+	if !g.options.Interpolate {
+		return g.viper, nil
+	}
+	settings, err := g.expandedSettings(g.viper, g.dotenv)
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(settings, g.layers.aliases, g.generation).viper, nil
+}
+
+// expand 展开配置项 key 的值，返回展开后的值和第一个错误，无法展开的部分保留原样
+// chain 为正在展开的配置项，用于检测循环引用
+func (in *interpolator) expand(key string, value interface{}, chain []string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return in.expandString(v, append(chain[:len(chain):len(chain)], key))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var firstErr error
+		m := make(map[string]interface{}, len(v))
+		for _, k := range keys {
+			expanded, err := in.expand(key+"."+k, v[k], chain)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			m[k] = expanded
+		}
+		return m, firstErr
+	case []interface{}:
+		var firstErr error
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := in.expand(key, item, chain)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			list[i] = expanded
+		}
+		return list, firstErr
+	case []string:
+		var firstErr error
+		list := make([]string, len(v))
+		for i, item := range v {
+			expanded, err := in.expand(key, item, chain)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			list[i] = toString(expanded)
+		}
+		return list, firstErr
+	}
+	return value, nil
+}
+
+// expandString 展开字符串中的引用，失败时返回原字符串
+func (in *interpolator) expandString(s string, chain []string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			return s, &InterpolationError{Key: chain[0], Chain: chain, Err: fmt.Errorf("引用 %s 缺少 }", s[i:])}
+		}
+		value, err := in.resolve(s[i+2:end], chain)
+		if err != nil {
+			return s, err
+		}
+		// 整个值只有一个引用时保留类型
+		if i == 0 && end == len(s)-1 {
+			return value, nil
+		}
+		b.WriteString(toString(value))
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// resolve 解析一个引用表达式（${ 和 } 之间的内容）
+func (in *interpolator) resolve(expr string, chain []string) (interface{}, error) {
+	name, def, hasDef := expr, "", false
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, hasDef = expr[:i], expr[i+2:], true
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &InterpolationError{Key: chain[0], Chain: chain, Err: fmt.Errorf("空引用 ${%s}", expr)}
+	}
+
+	value, ok, err := in.lookup(name, chain)
+	if err != nil {
+		return nil, err
+	}
+	if ok && (!hasDef || toString(value) != "") {
+		return value, nil
+	}
+	if hasDef {
+		return in.expandString(def, chain)
+	}
+	return nil, &InterpolationError{Key: chain[0], Chain: append(chain, name), Err: ErrUnresolvedReference}
+}
+
+// lookup 按名称查找环境变量或配置项，配置项的值会继续展开
+func (in *interpolator) lookup(name string, chain []string) (interface{}, bool, error) {
+	if !strings.Contains(name, ".") {
+		if v, ok := in.env(name); ok {
+			return v, true, nil
+		}
+	}
+
+	key := strings.ToLower(name)
+	if stringInSlice(key, chain) {
+		return nil, false, &InterpolationError{Key: chain[0], Chain: append(chain, key), Err: ErrReferenceCycle}
+	}
+	value, ok := in.get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	// 被引用配置项中无法展开的部分同样是错误
+	expanded, err := in.expand(key, value, chain)
+	if err != nil {
+		return nil, false, err
+	}
+	return expanded, true, nil
+}
+
+// closingBrace 返回与 start 之前的 ${ 匹配的 } 的位置，不存在时返回 -1
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// toString 将引用的值转换为字符串，无法转换的值（例如配置树）使用 fmt 格式化
+func toString(v interface{}) string {
+	if s, err := cast.ToStringE(v); err == nil {
+		return s
+	}
+	return fmt.Sprint(v)
+}
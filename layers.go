@@ -0,0 +1,102 @@
+package gconf
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// setting 一次 Set 或 SetDefault 调用
+type setting struct {
+	key   string
+	value interface{}
+}
+
+// layers 记录配置文件之外通过 API 写入的各层配置（默认值、Set 值、别名、环境变量绑定），
+// 用于构建与当前实例行为一致的暂存副本
+type layers struct {
+	defaults    []setting
+	overrides   []setting
+	aliases     []setting
+	envBindings [][]string
+}
+
+// recordSetting 记录一次设置，同时丢弃被它完全覆盖的旧记录（同一键或其子键）
+func recordSetting(settings []setting, key string, value interface{}) []setting {
+	key = strings.ToLower(key)
+	kept := settings[:0]
+	for _, s := range settings {
+		if s.key == key || strings.HasPrefix(s.key, key+".") {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return append(kept, setting{key: key, value: value})
+}
+
+// configureViper 按选项设置 viper 实例的配置文件查找规则和环境变量规则
+func configureViper(v *viper.Viper, options *Options) {
+	// 设置配置文件路径
+	for _, path := range options.ConfigPaths {
+		v.AddConfigPath(path)
+	}
+
+	// 设置配置文件名
+	v.SetConfigName(options.ConfigName)
+
+	// 设置配置文件类型
+	if options.ConfigType != "" {
+		v.SetConfigType(options.ConfigType)
+	}
+
+	// 设置环境变量
+	if options.AutomaticEnv {
+		v.AutomaticEnv()
+		if options.EnvPrefix != "" {
+			v.SetEnvPrefix(options.EnvPrefix)
+		}
+		if options.EnvKeyReplacer != nil {
+			v.SetEnvKeyReplacer(options.EnvKeyReplacer)
+		}
+	}
+
+	// 绑定命令行 flag
+	for _, f := range flagBindings(options) {
+		_ = v.BindFlagValue(f.key, f.value)
+	}
+}
+
+// newStaging 用新加载的配置文件构建暂存副本
+// 暂存副本包含当前实例的默认值、Set 值、别名和环境变量规则，对它的任何操作都不会影响当前配置
+func (g *Gconf) newStaging(layer *fileLayer) (*viper.Viper, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return newViper(g.options, layer, &g.layers)
+}
+
+// newViper 用配置文件层和 API 写入的各层配置构建新的 viper 实例
+func newViper(options *Options, layer *fileLayer, l *layers) (*viper.Viper, error) {
+	v := viper.New()
+	configureViper(v, options)
+	if layer.mainFile != "" {
+		v.SetConfigFile(layer.mainFile)
+	}
+	_ = v.MergeConfigMap(copySettings(layer.settings))
+
+	for _, s := range l.defaults {
+		v.SetDefault(s.key, s.value)
+	}
+	for _, s := range l.overrides {
+		v.Set(s.key, s.value)
+	}
+	for _, s := range l.aliases {
+		v.RegisterAlias(s.key, s.value.(string))
+	}
+	for _, keys := range l.envBindings {
+		if err := v.BindEnv(keys...); err != nil {
+			return nil, err
+		}
+	}
+	bindDotEnv(v, options, l.envBindings, &layer.dotenv)
+	return v, nil
+}
@@ -0,0 +1,49 @@
+package gconf
+
+import (
+	"errors"
+)
+
+// ErrClosed 配置实例已关闭
+var ErrClosed = errors.New("gconf: 配置实例已关闭")
+
+// Close 关闭配置实例：停止监听配置文件、释放文件句柄，并移除所有已注册的回调
+// 关闭后仍可读取最后一次加载的配置，但重新读取、写入配置文件等操作会返回 ErrClosed；
+// 重复关闭同样返回 ErrClosed
+func (g *Gconf) Close() error {
+	if g.parent != nil {
+		return nil
+	}
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return ErrClosed
+	}
+	g.closed = true
+	close(g.done)
+	watcher := g.watcher
+	g.watcher = nil
+	g.onChangeHandlers = nil
+	g.reloadErrorHandlers = nil
+	g.bindings = nil
+	g.mu.Unlock()
+
+	g.reloader.stop()
+
+	if watcher != nil {
+		return watcher.watcher.Close()
+	}
+	return nil
+}
+
+// Done 返回一个在配置实例关闭时被关闭的通道
+func (g *Gconf) Done() <-chan struct{} {
+	return g.done
+}
+
+// isClosed 判断配置实例是否已关闭
+func (g *Gconf) isClosed() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.closed
+}
@@ -0,0 +1,84 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "port: 1\n")
+
+	before := runtime.NumGoroutine()
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+
+	called := make(chan struct{}, 1)
+	conf.OnChange(func(e ChangeEvent) {
+		called <- struct{}{}
+	})
+
+	if err := conf.Close(); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+	if err := conf.Close(); err != ErrClosed {
+		t.Errorf("重复 Close 应返回 ErrClosed，得到 %v", err)
+	}
+	if err := conf.ReadInConfig(); err != ErrClosed {
+		t.Errorf("关闭后 ReadInConfig 应返回 ErrClosed，得到 %v", err)
+	}
+	if v := conf.GetInt("port"); v != 1 {
+		t.Errorf("关闭后仍应能读取最后的配置: 期望 1，得到 %d", v)
+	}
+
+	writeFile(t, file, "port: 2\n")
+	select {
+	case <-called:
+		t.Error("关闭后不应再触发回调")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// 监听 goroutine 应已退出
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("关闭后存在泄漏的 goroutine: 关闭前 %d，关闭后 %d", before, n)
+	}
+}
+
+func TestNewWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	conf, err := NewWithContext(ctx)
+	if err != nil {
+		t.Logf("创建配置实例时出错: %v", err)
+	}
+
+	cancel()
+	select {
+	case <-conf.Done():
+	case <-time.After(time.Second):
+		t.Fatal("ctx 结束后配置实例应被关闭")
+	}
+	if err := conf.WriteConfigAs(filepath.Join(os.TempDir(), "gconf-closed.yaml")); err != ErrClosed {
+		t.Errorf("关闭后 WriteConfigAs 应返回 ErrClosed，得到 %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package gconf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// fileLayer 从所有配置源加载并合并后的配置
+type fileLayer struct {
+	// 合并后的配置
+	settings map[string]interface{}
+	// 主配置文件（第一个不是配置目录的配置源中加载的第一个文件）
+	mainFile string
+	// 按合并顺序排列的所有配置文件
+	files []string
+	// 所有配置源内容的摘要，用于判断配置是否真的发生了变化
+	hash string
+	// 按合并顺序排列的各配置源（配置文件）的内容，用于 Explain
+	entries []sourceEntry
+	// .env 文件中的变量
+	dotenv map[string]dotEnvVar
+}
+
+// sourceEntry 一个配置源或配置文件加载的内容
+type sourceEntry struct {
+	// 配置源名称
+	name string
+	// 配置文件，非文件配置源为空
+	file string
+	// 配置文件的原始内容，用于查找配置项所在的行
+	data     []byte
+	settings map[string]interface{}
+}
+
+// loadFiles 依次读取所有配置源，按顺序深度合并
+// 由配置文件组成的配置源直接读取文件，以记录加载的文件并按文件内容计算摘要
+func (g *Gconf) loadFiles() (*fileLayer, error) {
+	layer := &fileLayer{
+		settings: make(map[string]interface{}),
+		dotenv:   make(map[string]dotEnvVar),
+	}
+
+	h := sha256.New()
+	for _, src := range g.sources {
+		h.Write([]byte(src.Name()))
+
+		fb, ok := src.(fileBacked)
+		if !ok {
+			settings, err := src.Load()
+			if err != nil {
+				return nil, fmt.Errorf("加载配置源 %s 失败: %w", src.Name(), err)
+			}
+			fmt.Fprint(h, settings)
+			mergeSettings(layer.settings, settings)
+			layer.entries = append(layer.entries, sourceEntry{name: src.Name(), settings: settings})
+			continue
+		}
+
+		files, err := fb.currentFiles()
+		if err != nil {
+			return nil, err
+		}
+		_, isDir := src.(*dirSource)
+		fs, isFileSource := src.(*fileSource)
+		_, isProfile := src.(*profileSource)
+		optional := isDir || isProfile || (isFileSource && fs.optional)
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				// 片段或可选的配置文件可能在列出后被删除，忽略即可
+				if optional && os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			// 被引用的文件同样计入摘要和已加载的文件，修改它们也会触发重新加载
+			err = loadIncludes(file, data, g.options.ConfigType, nil, func(f string, d []byte, settings map[string]interface{}) {
+				h.Write([]byte(f))
+				h.Write(d)
+				mergeSettings(layer.settings, settings)
+				layer.entries = append(layer.entries, sourceEntry{name: src.Name(), file: f, data: d, settings: settings})
+				layer.files = appendUnique(layer.files, f)
+			})
+			if err != nil {
+				return nil, err
+			}
+			if layer.mainFile == "" && !isDir {
+				layer.mainFile = file
+			}
+		}
+	}
+	for _, file := range g.options.DotEnvFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		h.Write([]byte(file))
+		h.Write(data)
+		if err := parseDotEnv(file, data, layer.dotenv, g.options.DotEnvOverride); err != nil {
+			return nil, err
+		}
+	}
+	layer.hash = hex.EncodeToString(h.Sum(nil))
+	return layer, nil
+}
+
+// applyFiles 用加载的配置替换当前实例的配置文件层，调用方需持有写锁
+func (g *Gconf) applyFiles(layer *fileLayer) {
+	if layer.mainFile != "" {
+		g.viper.SetConfigFile(layer.mainFile)
+	}
+	resetConfig(g.viper)
+	_ = g.viper.MergeConfigMap(copySettings(layer.settings))
+	g.mainFile = layer.mainFile
+	g.files = layer.files
+	g.fileSettings = layer.settings
+	g.sourceEntries = layer.entries
+	g.dotenv = layer.dotenv
+	bindDotEnv(g.viper, g.options, g.layers.envBindings, &g.dotenv)
+}
+
+// fileType 获取配置文件的格式，优先使用文件扩展名，否则使用 configType
+func fileType(filename, configType string) string {
+	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
+	if stringInSlice(ext, viper.SupportedExts) {
+		return ext
+	}
+	return configType
+}
+
+// findConfigFile 按 viper 的规则在 ConfigPaths 中查找主配置文件，未找到时返回空字符串
+func findConfigFile(options *Options) string {
+	for _, dir := range options.ConfigPaths {
+		dir = absPath(dir)
+		for _, ext := range viper.SupportedExts {
+			if file := filepath.Join(dir, options.ConfigName+"."+ext); isFile(file) {
+				return file
+			}
+		}
+		if options.ConfigType != "" {
+			if file := filepath.Join(dir, options.ConfigName); isFile(file) {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// parseConfig 按指定格式解析配置内容
+func parseConfig(data []byte, configType string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigType(configType)
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// resetConfig 清空 viper 实例的配置文件层
+// viper 没有直接清空的方法，ReadConfig 会先替换为空的配置再解析输入，空输入的解析结果可以忽略
+func resetConfig(v *viper.Viper) {
+	_ = v.ReadConfig(strings.NewReader(""))
+}
+
+// mergeSettings 将 src 深度合并到 dst，两边都是子树时递归合并，否则 src 覆盖 dst
+func mergeSettings(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				mergeSettings(dm, sm)
+				continue
+			}
+			dst[k] = copySettings(sm)
+			continue
+		}
+		dst[k] = sv
+	}
+}
+
+// copySettings 深拷贝配置树中的各级映射
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = copySettings(m)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// absPath 展开路径中的环境变量并转换为绝对路径
+func absPath(path string) string {
+	path = os.ExpandEnv(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// isDir 判断路径是否为已存在的目录（跟随符号链接）
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isFile 判断路径是否为已存在的普通文件（跟随符号链接）
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confDir := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "config.yaml"), "database:\n  host: localhost\n  port: 3306\nname: app\n")
+	writeFile(t, filepath.Join(confDir, "10-db.yaml"), "database:\n  host: db.internal\n  user: root\n")
+	writeFile(t, filepath.Join(confDir, "20-overrides.yaml"), "database:\n  user: admin\n")
+	writeFile(t, filepath.Join(confDir, "README.md"), "# 不匹配的文件\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithConfigDir(confDir, "*.yaml"),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if v := conf.GetString("database.host"); v != "db.internal" {
+		t.Errorf("片段应覆盖主配置: 期望 'db.internal'，得到 '%s'", v)
+	}
+	if v := conf.GetInt("database.port"); v != 3306 {
+		t.Errorf("深度合并应保留主配置中的值: 期望 3306，得到 %d", v)
+	}
+	if v := conf.GetString("database.user"); v != "admin" {
+		t.Errorf("后面的片段应覆盖前面的片段: 期望 'admin'，得到 '%s'", v)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+	waitChange := func(key string, want interface{}) {
+		t.Helper()
+		deadline := time.After(3 * time.Second)
+		for {
+			select {
+			case ev := <-events:
+				if c, ok := ev.Change(key); ok && c.NewValue == want {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("等待 %s 变为 %v 超时", key, want)
+			}
+		}
+	}
+
+	// 新增片段
+	writeFile(t, filepath.Join(confDir, "30-new.yaml"), "name: new\n")
+	waitChange("name", "new")
+
+	// 删除片段
+	if err := os.Remove(filepath.Join(confDir, "20-overrides.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	waitChange("database.user", "root")
+}
+
+func TestReadInConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confDir := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(confDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "$include: common.yaml\na: 1\n")
+	writeFile(t, filepath.Join(dir, "common.yaml"), "c: 3\n")
+	writeFile(t, filepath.Join(confDir, "10.yaml"), "b: 2\n")
+
+	conf, err := New(WithConfigPaths(dir), WithConfigDir(confDir, "*.yaml"))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	called := false
+	conf.OnChange(func(e ChangeEvent) {
+		called = true
+	})
+
+	writeFile(t, file, "$include: common.yaml\na: 10\n")
+	if err := conf.ReadInConfig(); err != nil {
+		t.Fatalf("重新读取配置失败: %v", err)
+	}
+	if v := conf.GetInt("a"); v != 10 {
+		t.Errorf("期望 10，得到 %d", v)
+	}
+	if v := conf.GetInt("b"); v != 2 {
+		t.Errorf("配置目录中的片段应保留: 得到 %d", v)
+	}
+	if v := conf.GetInt("c"); v != 3 {
+		t.Errorf("引用的文件应保留: 得到 %d", v)
+	}
+	if conf.IsSet(IncludeKey) {
+		t.Errorf("%s 不应出现在配置中", IncludeKey)
+	}
+	if e := conf.Explain("b"); e.Source == nil || e.Source.File != filepath.Join(confDir, "10.yaml") {
+		t.Errorf("Explain 结果不正确: %s", e)
+	}
+	if called {
+		t.Error("ReadInConfig 不应通知回调")
+	}
+
+	// 内容未变化，Reload 不应再次生效
+	if changed, err := conf.Reload(context.Background()); changed || err != nil {
+		t.Errorf("期望 false, nil，得到 %v, %v", changed, err)
+	}
+}
+
+func TestMergeSettings(t *testing.T) {
+	dst := map[string]interface{}{
+		"a": map[string]interface{}{"x": 1, "y": 2},
+		"b": 1,
+	}
+	src := map[string]interface{}{
+		"a": map[string]interface{}{"y": 3, "z": 4},
+		"b": map[string]interface{}{"c": 5},
+	}
+	mergeSettings(dst, src)
+
+	a := dst["a"].(map[string]interface{})
+	if a["x"] != 1 || a["y"] != 3 || a["z"] != 4 {
+		t.Errorf("子树合并不正确: %v", a)
+	}
+	if b, ok := dst["b"].(map[string]interface{}); !ok || b["c"] != 5 {
+		t.Errorf("值应被子树覆盖: %v", dst["b"])
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.yaml")
+	region := filepath.Join(dir, "region.yaml")
+	local := filepath.Join(dir, "local.yaml")
+	writeFile(t, base, "server:\n  host: base\n  port: 8080\nregion: none\n")
+	writeFile(t, region, "server:\n  host: region\nregion: cn\n")
+
+	conf, err := New(
+		WithRequiredConfigFiles(base),
+		WithConfigFiles(region, local),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if v := conf.GetString("server.host"); v != "region" {
+		t.Errorf("后面的文件应覆盖前面的文件: 期望 region，得到 %s", v)
+	}
+	if v := conf.GetInt("server.port"); v != 8080 {
+		t.Errorf("深度合并应保留前面文件中的值: 期望 8080，得到 %d", v)
+	}
+	if files := conf.ConfigFilesUsed(); len(files) != 2 || files[0] != base || files[1] != region {
+		t.Errorf("ConfigFilesUsed: 期望 [%s %s]，得到 %v", base, region, files)
+	}
+	if v := conf.ConfigFileUsed(); v != base {
+		t.Errorf("ConfigFileUsed: 期望 %s，得到 %s", base, v)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	// 可选的文件之后被创建
+	writeFile(t, local, "server:\n  host: local\n")
+	select {
+	case <-events:
+		if v := conf.GetString("server.host"); v != "local" {
+			t.Errorf("期望 local，得到 %s", v)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待可选配置文件创建后的重新加载超时")
+	}
+	if files := conf.ConfigFilesUsed(); len(files) != 3 {
+		t.Errorf("期望加载 3 个配置文件，得到 %v", files)
+	}
+
+	if _, err := New(WithRequiredConfigFiles(filepath.Join(dir, "missing.yaml"))); err == nil {
+		t.Error("必须存在的配置文件不存在时应返回错误")
+	}
+}
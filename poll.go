@@ -0,0 +1,178 @@
+package gconf
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchMode 监听配置文件变化的方式
+type WatchMode int
+
+const (
+	// WatchNotify 使用 fsnotify（inotify、kqueue 等）监听文件事件（默认）
+	WatchNotify WatchMode = iota
+	// WatchPoll 定时轮询配置文件，适用于 NFS、部分 FUSE 等不产生文件事件的文件系统
+	WatchPoll
+	// WatchAuto 优先使用 fsnotify，无法建立监听时退回轮询
+	WatchAuto
+)
+
+// DefaultPollInterval 未指定轮询间隔时使用的默认值
+const DefaultPollInterval = 5 * time.Second
+
+// WithWatchMode 设置监听配置文件变化的方式并启用监听
+// interval 为轮询间隔，只在轮询时使用，0 表示使用 DefaultPollInterval
+func WithWatchMode(mode WatchMode, interval time.Duration) Option {
+	return func(o *Options) {
+		o.WatchConfig = true
+		o.WatchMode = mode
+		o.PollInterval = interval
+	}
+}
+
+// fileStamp 轮询时记录的文件状态
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// filePoller 定时比较配置文件的修改时间、大小和内容摘要
+// 只比较修改时间和大小会漏掉同一秒内长度不变的修改（部分网络文件系统的时间精度很低），因此还会比较内容
+type filePoller struct {
+	g        *Gconf
+	interval time.Duration
+	// 上一次轮询时每个配置文件的状态
+	stamps map[string]fileStamp
+}
+
+// startWatch 按 WatchMode 开始监听配置文件变化
+func (g *Gconf) startWatch() error {
+	hasFiles := false
+	for _, src := range g.sources {
+		if _, ok := src.(fileBacked); ok {
+			hasFiles = true
+		}
+	}
+	// 只有自定义配置源时由 watchSources 负责监听
+	if !hasFiles {
+		return nil
+	}
+
+	switch g.options.WatchMode {
+	case WatchPoll:
+		g.pollConfig()
+		return nil
+	case WatchAuto:
+		if err := g.watchConfig(); err != nil {
+			if g.options.Debug {
+				log.Printf("[gconf] 无法使用文件事件监听配置文件，改为轮询: %v", err)
+			}
+			g.pollConfig()
+		}
+		return nil
+	default:
+		return g.watchConfig()
+	}
+}
+
+// pollConfig 定时轮询配置文件，发现变化时重新加载配置并通知回调
+func (g *Gconf) pollConfig() {
+	p := &filePoller{
+		g:        g,
+		interval: g.options.PollInterval,
+	}
+	if p.interval <= 0 {
+		p.interval = DefaultPollInterval
+	}
+	p.stamps = p.scan()
+	go p.run()
+}
+
+// run 按轮询间隔检查配置文件，直到配置实例关闭
+func (p *filePoller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stamps := p.scan()
+			if e, changed := p.compare(stamps); changed {
+				p.g.scheduleReload(e)
+			}
+			p.stamps = stamps
+		case <-p.g.done:
+			return
+		}
+	}
+}
+
+// files 返回需要轮询的文件：所有由配置文件组成的配置源当前包含的文件（主配置文件尚未找到时重新查找）
+// 以及上一次加载时引用的配置文件
+func (p *filePoller) files() []string {
+	files := make([]string, 0)
+	for _, src := range p.g.sources {
+		fb, ok := src.(fileBacked)
+		if !ok {
+			continue
+		}
+		current, err := fb.currentFiles()
+		if err != nil && p.g.options.Debug {
+			log.Printf("[gconf] 轮询配置源 %s 出错: %v", src.Name(), err)
+		}
+		for _, file := range current {
+			files = appendUnique(files, file)
+		}
+	}
+	p.g.mu.RLock()
+	for _, file := range p.g.files {
+		files = appendUnique(files, file)
+	}
+	p.g.mu.RUnlock()
+	return files
+}
+
+// scan 读取所有配置文件的当前状态，不存在或无法读取的文件不会出现在结果中
+func (p *filePoller) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, file := range p.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		stamps[file] = fileStamp{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			hash:    sha256.Sum256(data),
+		}
+	}
+	return stamps
+}
+
+// compare 比较两次轮询的结果，返回描述第一个变化的文件事件
+func (p *filePoller) compare(stamps map[string]fileStamp) (fsnotify.Event, bool) {
+	for file, stamp := range stamps {
+		old, ok := p.stamps[file]
+		if !ok {
+			return fsnotify.Event{Name: file, Op: fsnotify.Create}, true
+		}
+		if stamp != old {
+			return fsnotify.Event{Name: file, Op: fsnotify.Write}, true
+		}
+	}
+	for file := range p.stamps {
+		if _, ok := stamps[file]; !ok {
+			return fsnotify.Event{Name: file, Op: fsnotify.Remove}, true
+		}
+	}
+	return fsnotify.Event{}, false
+}
@@ -0,0 +1,98 @@
+package gconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchPoll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchMode(WatchPoll, 20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	expectPort := func(port int) {
+		t.Helper()
+		select {
+		case <-events:
+			if v := conf.GetInt("server.port"); v != port {
+				t.Errorf("期望 %d，得到 %d", port, v)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待轮询触发的重新加载超时")
+		}
+	}
+
+	// 长度和修改时间都不变，只有内容变化
+	writeFile(t, file, "server:\n  port: 9090\n")
+	if err := os.Chtimes(file, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	expectPort(9090)
+
+	writeFile(t, file, "server:\n  port: 10000\n")
+	expectPort(10000)
+}
+
+func TestWatchAutoFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 配置文件尚不存在，无法建立文件事件监听，应退回轮询
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchMode(WatchAuto, 20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	select {
+	case e := <-events:
+		if c, ok := e.Change("server.port"); !ok || c.Type != ChangeAdded {
+			t.Errorf("期望新增 server.port: %+v", e.Changes)
+		}
+		if got := conf.ConfigFileUsed(); got != file {
+			t.Errorf("期望使用 %s，得到 %s", file, got)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待轮询发现新配置文件超时")
+	}
+}
@@ -0,0 +1,121 @@
+package gconf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// WithProfile 设置激活的环境配置（profile），可以同时激活多个
+// 每个配置文件之后依次合并同目录下的 profile 覆盖文件，例如 config.yaml 之后是 config.prod.yaml，
+// 覆盖文件不存在时跳过
+func WithProfile(profiles ...string) Option {
+	return func(o *Options) {
+		o.Profiles = append(o.Profiles, profiles...)
+	}
+}
+
+// WithProfileEnv 从环境变量（例如 APP_PROFILE）读取激活的 profile，多个 profile 以逗号分隔
+// 环境变量不为空时代替 WithProfile 设置的 profile
+func WithProfileEnv(name string) Option {
+	return func(o *Options) {
+		o.ProfileEnv = name
+	}
+}
+
+// Profiles 返回激活的 profile，按合并顺序排列
+func (g *Gconf) Profiles() []string {
+	if g.parent != nil {
+		return g.parent.Profiles()
+	}
+	return append([]string(nil), g.profiles...)
+}
+
+// activeProfiles 按选项和环境变量确定激活的 profile
+func activeProfiles(options *Options) []string {
+	raw := options.Profiles
+	if options.ProfileEnv != "" {
+		if v := os.Getenv(options.ProfileEnv); v != "" {
+			raw = strings.Split(v, ",")
+		}
+	}
+	profiles := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = appendUnique(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// profileSource 配置文件的 profile 覆盖文件，例如 config.yaml 对应的 config.prod.yaml
+type profileSource struct {
+	base    fileBacked
+	profile string
+}
+
+func (s *profileSource) Name() string {
+	return "profile:" + s.profile
+}
+
+func (s *profileSource) Load() (map[string]interface{}, error) {
+	files, err := s.currentFiles()
+	if err != nil {
+		return nil, err
+	}
+	return readFiles(files, "")
+}
+
+// currentFiles 返回基础配置文件对应的、已存在的覆盖文件
+func (s *profileSource) currentFiles() ([]string, error) {
+	files := make([]string, 0)
+	for _, candidate := range s.candidates() {
+		if isFile(candidate) {
+			files = append(files, candidate)
+		}
+	}
+	return files, nil
+}
+
+// candidates 返回每个基础配置文件对应的覆盖文件路径，不论是否存在
+// 优先使用与基础配置文件相同的扩展名，其次是 viper 支持的其他扩展名
+func (s *profileSource) candidates() []string {
+	files, _ := s.base.currentFiles()
+	candidates := make([]string, 0, len(files))
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		stem := strings.TrimSuffix(file, ext) + "." + s.profile
+		candidate := stem + ext
+		if !isFile(candidate) {
+			for _, e := range viper.SupportedExts {
+				if isFile(stem + "." + e) {
+					candidate = stem + "." + e
+					break
+				}
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// withProfiles 在每个配置文件配置源之后加入激活的 profile 覆盖文件
+func withProfiles(sources []Source, profiles []string) []Source {
+	if len(profiles) == 0 {
+		return sources
+	}
+	result := make([]Source, 0, len(sources)*(len(profiles)+1))
+	for _, src := range sources {
+		result = append(result, src)
+		if fb, ok := src.(fileBacked); ok {
+			if _, isDir := src.(*dirSource); !isDir {
+				for _, p := range profiles {
+					result = append(result, &profileSource{base: fb, profile: p})
+				}
+			}
+		}
+	}
+	return result
+}
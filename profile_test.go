@@ -0,0 +1,69 @@
+package gconf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "config.yaml")
+	prod := filepath.Join(dir, "config.prod.yaml")
+	writeFile(t, base, "server:\n  host: localhost\n  port: 8080\nlog:\n  level: debug\n")
+	writeFile(t, prod, "server:\n  host: prod.internal\nlog:\n  level: warn\n")
+	writeFile(t, filepath.Join(dir, "config.eu.json"), `{"server": {"port": 9090}, "log": {"level": "error"}}`)
+	writeFile(t, filepath.Join(dir, "config.dev.yaml"), "log:\n  level: trace\n")
+
+	os.Setenv("GCONF_TEST_PROFILE", "prod, eu")
+	defer os.Unsetenv("GCONF_TEST_PROFILE")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithProfile("dev"),
+		WithProfileEnv("GCONF_TEST_PROFILE"),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if p := conf.Profiles(); !reflect.DeepEqual(p, []string{"prod", "eu"}) {
+		t.Errorf("环境变量应代替 WithProfile: %v", p)
+	}
+	if v := conf.GetString("server.host"); v != "prod.internal" {
+		t.Errorf("期望 prod.internal，得到 %s", v)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("其他扩展名的覆盖文件也应合并: 期望 9090，得到 %d", v)
+	}
+	if v := conf.GetString("log.level"); v != "error" {
+		t.Errorf("后激活的 profile 优先: 期望 error，得到 %s", v)
+	}
+	if v := conf.ConfigFileUsed(); v != base {
+		t.Errorf("主配置文件应为基础文件: 期望 %s，得到 %s", base, v)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+	writeFile(t, prod, "server:\n  host: prod2.internal\n")
+	select {
+	case <-events:
+		if v := conf.GetString("server.host"); v != "prod2.internal" {
+			t.Errorf("期望 prod2.internal，得到 %s", v)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待覆盖文件变化后的重新加载超时")
+	}
+}
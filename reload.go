@@ -0,0 +1,53 @@
+package gconf
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WithReloadSignal 收到指定信号（例如 syscall.SIGHUP）时重新加载配置
+func WithReloadSignal(sigs ...os.Signal) Option {
+	return func(o *Options) {
+		o.ReloadSignals = append(o.ReloadSignals, sigs...)
+	}
+}
+
+// Reload 立即重新读取所有配置文件，与监听配置文件变化时一样执行校验并通知回调
+// 返回配置是否发生变化；读取或校验失败时保留原配置并返回 *ReloadError，
+// 配置已生效但有回调执行失败时返回 true 和 *DispatchError。
+// Reload 会等待所有回调执行结束，回调中可以再次调用 Reload；文件变化、轮询和信号触发的重新加载不等待回调。
+// ctx 在加载期间结束时放弃本次重新加载；在等待回调期间结束时配置已经生效，Reload 不再等待并返回 ctx.Err()，
+// 未结束的回调在后台继续执行（返回值 changed 仍表示配置是否发生变化）。
+// 未启用 WithWatchConfig 时可以用它按需刷新配置
+func (g *Gconf) Reload(ctx context.Context) (bool, error) {
+	if g.parent != nil {
+		return g.parent.Reload(ctx)
+	}
+	return g.reload(ctx, fsnotify.Event{Name: g.ConfigFileUsed()})
+}
+
+// watchSignals 收到重新加载信号时重新加载配置，直到配置实例关闭
+func (g *Gconf) watchSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, g.options.ReloadSignals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				if g.options.Debug {
+					log.Printf("[gconf] 收到信号 %v，重新加载配置", sig)
+				}
+				// 失败已通过 OnReloadError 和 OnHandlerError 报告
+				g.reloadAsync(fsnotify.Event{Name: g.ConfigFileUsed()})
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,220 @@
+package gconf
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithValidator(func(settings map[string]interface{}) error {
+			server, _ := settings["server"].(map[string]interface{})
+			if server["port"] == 0 {
+				return errors.New("server.port 不能为 0")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	var last ChangeEvent
+	conf.OnChange(func(e ChangeEvent) {
+		last = e
+	})
+
+	changed, err := conf.Reload(context.Background())
+	if changed || err != nil {
+		t.Errorf("配置文件未变化时应返回 false, nil，得到 %v, %v", changed, err)
+	}
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	changed, err = conf.Reload(context.Background())
+	if !changed || err != nil {
+		t.Fatalf("配置文件变化时应返回 true, nil，得到 %v, %v", changed, err)
+	}
+	if c, ok := last.Change("server.port"); !ok || c.NewValue != 9090 {
+		t.Errorf("Reload 应同步通知回调: %+v", last.Changes)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("期望 9090，得到 %d", v)
+	}
+
+	writeFile(t, file, "server:\n  port: 0\n")
+	for i := 0; i < 2; i++ {
+		changed, err = conf.Reload(context.Background())
+		var reloadErr *ReloadError
+		if changed || !errors.As(err, &reloadErr) {
+			t.Errorf("校验失败时应返回 false 和 ReloadError，得到 %v, %v", changed, err)
+		}
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("校验失败后应保留原配置: 期望 9090，得到 %d", v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writeFile(t, file, "server:\n  port: 7070\n")
+	if _, err := conf.Reload(ctx); err != context.Canceled {
+		t.Errorf("ctx 已取消时应返回 context.Canceled，得到 %v", err)
+	}
+
+	conf.Close()
+	if _, err := conf.Reload(context.Background()); err != ErrClosed {
+		t.Errorf("关闭后应返回 ErrClosed，得到 %v", err)
+	}
+}
+
+func TestReloadFromHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(WithConfigPaths(dir))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	calls := 0
+	conf.OnChange(func(e ChangeEvent) {
+		calls++
+		if calls == 1 {
+			// 回调中再次重新加载不应死锁
+			if err := ioutil.WriteFile(file, []byte("server:\n  port: 7070\n"), 0644); err != nil {
+				t.Errorf("写入文件失败: %v", err)
+			}
+			if _, err := conf.Reload(context.Background()); err != nil {
+				t.Errorf("回调中重新加载失败: %v", err)
+			}
+		}
+	})
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	done := make(chan error, 1)
+	go func() {
+		_, err := conf.Reload(context.Background())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("重新加载失败: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("回调中调用 Reload 导致死锁")
+	}
+	if calls != 2 {
+		t.Errorf("期望回调执行 2 次，得到 %d", calls)
+	}
+	if v := conf.GetInt("server.port"); v != 7070 {
+		t.Errorf("期望 7070，得到 %d", v)
+	}
+}
+
+func TestReloadContextHandlers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(WithConfigPaths(dir))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	conf.OnChange(func(e ChangeEvent) {
+		<-release
+	})
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	var changed bool
+	go func() {
+		changed, err = conf.Reload(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ctx 结束后 Reload 不应继续等待回调")
+	}
+	if !changed || err != context.DeadlineExceeded {
+		t.Errorf("期望 true, context.DeadlineExceeded，得到 %v, %v", changed, err)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("配置应已生效: 期望 9090，得到 %d", v)
+	}
+}
+
+func TestReloadSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(WithConfigPaths(dir), WithReloadSignal(syscall.SIGHUP))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	writeFile(t, file, "server:\n  port: 9090\n")
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("不支持发送 SIGHUP: %v", err)
+	}
+
+	select {
+	case <-events:
+		if v := conf.GetInt("server.port"); v != 9090 {
+			t.Errorf("期望 9090，得到 %d", v)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待信号触发的重新加载超时")
+	}
+}
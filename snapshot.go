@@ -0,0 +1,187 @@
+package gconf
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Snapshot 配置的只读快照
+// 快照冻结在某一代配置上，之后的重新加载和 Set 都不会影响它，
+// 适合在一次请求等工作单元内读取多个相互关联的配置项，可以安全地并发读取
+type Snapshot struct {
+	viper      *viper.Viper
+	generation uint64
+}
+
+// newSnapshot 用完整配置构建快照
+func newSnapshot(settings map[string]interface{}, aliases []setting, generation uint64) *Snapshot {
+	v := viper.New()
+	_ = v.MergeConfigMap(settings)
+	for _, s := range aliases {
+		v.RegisterAlias(s.key, s.value.(string))
+	}
+	return &Snapshot{viper: v, generation: generation}
+}
+
+// Snapshot 获取当前配置的只读快照
+// 配置未发生变化时多次调用返回同一个快照
+func (g *Gconf) Snapshot() *Snapshot {
+	if g.parent != nil {
+		return g.parent.Snapshot().Sub(g.prefix)
+	}
+	g.mu.RLock()
+	snapshot := g.snapshot
+	current := snapshot != nil && snapshot.generation == g.generation
+	g.mu.RUnlock()
+	if current {
+		return snapshot
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// 其他 goroutine 可能已经重建了快照
+	if g.snapshot == nil || g.snapshot.generation != g.generation {
+		g.snapshot = newSnapshot(g.allSettings(), g.layers.aliases, g.generation)
+	}
+	return g.snapshot
+}
+
+// Generation 获取当前配置的代数，每次重新加载或修改配置后递增
+func (g *Gconf) Generation() uint64 {
+	if g.parent != nil {
+		return g.parent.Generation()
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.generation
+}
+
+// Generation 获取快照对应的配置代数
+func (s *Snapshot) Generation() uint64 {
+	return s.generation
+}
+
+// Sub 获取子配置树的快照，子配置树不存在时返回空快照
+func (s *Snapshot) Sub(key string) *Snapshot {
+	v := s.viper.Sub(key)
+	if v == nil {
+		v = viper.New()
+	}
+	return &Snapshot{viper: v, generation: s.generation}
+}
+
+// Get 获取配置值
+func (s *Snapshot) Get(key string) interface{} {
+	return s.viper.Get(key)
+}
+
+// GetString 获取字符串类型配置
+func (s *Snapshot) GetString(key string) string {
+	return s.viper.GetString(key)
+}
+
+// GetBool 获取布尔类型配置
+func (s *Snapshot) GetBool(key string) bool {
+	return s.viper.GetBool(key)
+}
+
+// GetInt 获取整数类型配置
+func (s *Snapshot) GetInt(key string) int {
+	return s.viper.GetInt(key)
+}
+
+// GetInt32 获取 int32 类型配置
+func (s *Snapshot) GetInt32(key string) int32 {
+	return s.viper.GetInt32(key)
+}
+
+// GetInt64 获取 int64 类型配置
+func (s *Snapshot) GetInt64(key string) int64 {
+	return s.viper.GetInt64(key)
+}
+
+// GetUint 获取无符号整数类型配置
+func (s *Snapshot) GetUint(key string) uint {
+	return s.viper.GetUint(key)
+}
+
+// GetUint32 获取 uint32 类型配置
+func (s *Snapshot) GetUint32(key string) uint32 {
+	return s.viper.GetUint32(key)
+}
+
+// GetUint64 获取 uint64 类型配置
+func (s *Snapshot) GetUint64(key string) uint64 {
+	return s.viper.GetUint64(key)
+}
+
+// GetFloat64 获取浮点数类型配置
+func (s *Snapshot) GetFloat64(key string) float64 {
+	return s.viper.GetFloat64(key)
+}
+
+// GetTime 获取时间类型配置
+func (s *Snapshot) GetTime(key string) time.Time {
+	return s.viper.GetTime(key)
+}
+
+// GetDuration 获取时间间隔类型配置
+func (s *Snapshot) GetDuration(key string) time.Duration {
+	return s.viper.GetDuration(key)
+}
+
+// GetStringSlice 获取字符串切片类型配置
+func (s *Snapshot) GetStringSlice(key string) []string {
+	return s.viper.GetStringSlice(key)
+}
+
+// GetStringMap 获取字符串映射类型配置
+func (s *Snapshot) GetStringMap(key string) map[string]interface{} {
+	return s.viper.GetStringMap(key)
+}
+
+// GetStringMapString 获取字符串到字符串映射类型配置
+func (s *Snapshot) GetStringMapString(key string) map[string]string {
+	return s.viper.GetStringMapString(key)
+}
+
+// GetStringMapStringSlice 获取字符串到字符串切片映射类型配置
+func (s *Snapshot) GetStringMapStringSlice(key string) map[string][]string {
+	return s.viper.GetStringMapStringSlice(key)
+}
+
+// GetSizeInBytes 获取字节大小类型配置（支持 KB, MB, GB 等）
+func (s *Snapshot) GetSizeInBytes(key string) uint {
+	return s.viper.GetSizeInBytes(key)
+}
+
+// IsSet 检查配置键是否存在
+func (s *Snapshot) IsSet(key string) bool {
+	return s.viper.IsSet(key)
+}
+
+// AllKeys 获取所有配置键
+func (s *Snapshot) AllKeys() []string {
+	return s.viper.AllKeys()
+}
+
+// AllSettings 获取所有配置
+func (s *Snapshot) AllSettings() map[string]interface{} {
+	return s.viper.AllSettings()
+}
+
+// Unmarshal 将配置解析到结构体
+func (s *Snapshot) Unmarshal(rawVal interface{}) error {
+	return s.viper.Unmarshal(rawVal)
+}
+
+// UnmarshalKey 将指定键的配置解析到结构体
+func (s *Snapshot) UnmarshalKey(key string, rawVal interface{}) error {
+	return s.viper.UnmarshalKey(key, rawVal)
+}
+
+// UnmarshalExact 严格解析配置到结构体（结构体中未定义的字段会报错）
+func (s *Snapshot) UnmarshalExact(rawVal interface{}) error {
+	return s.viper.UnmarshalExact(rawVal)
+}
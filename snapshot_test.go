@@ -0,0 +1,48 @@
+package gconf
+
+import (
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	conf, _ := New()
+
+	conf.SetDefault("database.host", "localhost")
+	conf.Set("database.port", 3306)
+	conf.RegisterAlias("db_port", "database.port")
+
+	snap := conf.Snapshot()
+	if snap != conf.Snapshot() {
+		t.Error("配置未变化时应返回同一个快照")
+	}
+	if snap.Generation() != conf.Generation() {
+		t.Errorf("快照代数应为 %d，得到 %d", conf.Generation(), snap.Generation())
+	}
+
+	conf.Set("database.host", "db.example.com")
+	conf.Set("database.port", 5432)
+
+	if v := snap.GetString("database.host"); v != "localhost" {
+		t.Errorf("快照不应受后续修改影响: 期望 'localhost'，得到 '%s'", v)
+	}
+	if v := snap.GetInt("db_port"); v != 3306 {
+		t.Errorf("快照应支持别名: 期望 3306，得到 %d", v)
+	}
+
+	next := conf.Snapshot()
+	if next == snap || next.Generation() <= snap.Generation() {
+		t.Error("配置变化后应生成新的快照")
+	}
+
+	type Database struct {
+		Host string `mapstructure:"host"`
+		Port int    `mapstructure:"port"`
+	}
+	var db Database
+	if err := next.UnmarshalKey("database", &db); err != nil {
+		t.Fatalf("UnmarshalKey 失败: %v", err)
+	}
+	if db.Host != "db.example.com" || db.Port != 5432 {
+		t.Errorf("快照解析结果不正确: %+v", db)
+	}
+}
@@ -0,0 +1,365 @@
+package gconf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Source 配置源
+// 配置源按 WithSources 中的顺序深度合并，后面的配置源覆盖前面的配置源；
+// 合并结果之上依次是环境变量（AutomaticEnv、BindEnv）和 Set 的值，之下是 SetDefault 的默认值
+type Source interface {
+	// Name 配置源名称，用于日志、错误信息和 Layers
+	Name() string
+	// Load 读取配置，返回以 . 分隔层级的嵌套配置树
+	Load() (map[string]interface{}, error)
+}
+
+// WatchableSource 可以监听变化的配置源
+// 启用 WithWatchConfig 时，Gconf 会调用 Watch 监听配置源，配置源变化时调用 changed 触发重新加载；
+// ctx 在配置实例关闭时结束，Watch 应在 ctx 结束后返回；Watch 在 ctx 结束前返回错误时通过 OnReloadError 报告
+type WatchableSource interface {
+	Source
+	Watch(ctx context.Context, changed func()) error
+}
+
+// fileBacked 由配置文件组成的内置配置源，由 Gconf 读取文件并监听文件变化
+type fileBacked interface {
+	Source
+	// currentFiles 返回配置源当前包含的文件，按合并顺序排列
+	currentFiles() ([]string, error)
+}
+
+// WithSources 设置配置源，按优先级从低到高排列
+// 设置后 ConfigFiles、ConfigPaths、ConfigName、ConfigDir 等选项不再生效，它们只是默认配置源的简写
+func WithSources(sources ...Source) Option {
+	return func(o *Options) {
+		o.Sources = append(o.Sources, sources...)
+	}
+}
+
+// FileSource 读取单个配置文件的配置源，格式由扩展名决定，无法识别时使用 ConfigType
+// 文件不存在时返回错误
+func FileSource(path string) Source {
+	return &fileSource{path: absPath(path)}
+}
+
+// OptionalFileSource 读取单个配置文件的配置源，文件不存在时为空配置
+// 启用监听时，文件之后被创建也会触发重新加载
+func OptionalFileSource(path string) Source {
+	return &fileSource{path: absPath(path), optional: true}
+}
+
+// DirSource 读取目录中所有匹配 glob 的配置文件的配置源，按文件名的字典序合并
+// 目录不存在或没有匹配的文件时为空配置
+func DirSource(dir, glob string) Source {
+	if glob == "" {
+		glob = "*"
+	}
+	return &dirSource{dir: absPath(dir), glob: glob}
+}
+
+// EnvSource 读取带前缀的环境变量的配置源
+// 环境变量名去掉前缀和分隔符后转为小写，分隔符替换为 . 作为配置键，
+// 例如前缀 APP、分隔符 "__" 时 APP__SERVER__PORT 对应 server.port；separator 为空时使用 "_"
+func EnvSource(prefix, separator string) Source {
+	if separator == "" {
+		separator = "_"
+	}
+	return &envSource{prefix: prefix, separator: separator}
+}
+
+// MapSource 内存中的配置源，常用于提供一组默认值或测试
+// settings 在创建时被复制，之后对它的修改不会生效
+func MapSource(name string, settings map[string]interface{}) Source {
+	return &mapSource{name: name, settings: copySettings(settings)}
+}
+
+// fileSource 单个配置文件
+type fileSource struct {
+	path     string
+	optional bool
+}
+
+func (s *fileSource) Name() string {
+	return "file:" + s.path
+}
+
+func (s *fileSource) Load() (map[string]interface{}, error) {
+	files, err := s.currentFiles()
+	if err != nil {
+		return nil, err
+	}
+	return readFiles(files, "")
+}
+
+// currentFiles 可选的配置文件不存在时不包含任何文件
+func (s *fileSource) currentFiles() ([]string, error) {
+	if s.optional && !isFile(s.path) {
+		return nil, nil
+	}
+	return []string{s.path}, nil
+}
+
+// dirSource 配置片段目录
+type dirSource struct {
+	dir  string
+	glob string
+}
+
+func (s *dirSource) Name() string {
+	return "dir:" + filepath.Join(s.dir, s.glob)
+}
+
+func (s *dirSource) Load() (map[string]interface{}, error) {
+	files, err := s.currentFiles()
+	if err != nil {
+		return nil, err
+	}
+	return readFiles(files, "")
+}
+
+// currentFiles 按字典序列出目录中匹配的文件
+func (s *dirSource) currentFiles() ([]string, error) {
+	// filepath.Glob 返回的结果已按字典序排列
+	matches, err := filepath.Glob(filepath.Join(s.dir, s.glob))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if isFile(m) {
+			files = append(files, m)
+		}
+	}
+	return files, nil
+}
+
+// matches 判断文件是否属于该目录配置源
+func (s *dirSource) matches(file string) bool {
+	if filepath.Dir(file) != s.dir {
+		return false
+	}
+	ok, _ := filepath.Match(s.glob, filepath.Base(file))
+	return ok
+}
+
+// configFileSource 按 ConfigPaths、ConfigName 查找的主配置文件
+// 主配置文件在第一次找到后固定下来，之后读取失败（例如被删除）会返回错误
+type configFileSource struct {
+	g *Gconf
+}
+
+func (s *configFileSource) Name() string {
+	if file := s.file(); file != "" {
+		return "file:" + file
+	}
+	return "file:" + s.g.options.ConfigName
+}
+
+func (s *configFileSource) Load() (map[string]interface{}, error) {
+	files, err := s.currentFiles()
+	if err != nil {
+		return nil, err
+	}
+	return readFiles(files, s.g.options.ConfigType)
+}
+
+func (s *configFileSource) currentFiles() ([]string, error) {
+	if file := s.file(); file != "" {
+		return []string{file}, nil
+	}
+	return nil, nil
+}
+
+// file 返回已固定的主配置文件，尚未找到时重新查找
+func (s *configFileSource) file() string {
+	s.g.mu.RLock()
+	mainFile := s.g.mainFile
+	s.g.mu.RUnlock()
+	if mainFile == "" {
+		mainFile = findConfigFile(s.g.options)
+	}
+	return mainFile
+}
+
+// envSource 带前缀的环境变量
+type envSource struct {
+	prefix    string
+	separator string
+}
+
+func (s *envSource) Name() string {
+	return "env:" + s.prefix
+}
+
+func (s *envSource) Load() (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += s.separator
+	}
+	environ := os.Environ()
+	// 排序后 APP_SERVER_PORT 总在 APP_SERVER 之后处理，结果与环境变量的顺序无关
+	sort.Strings(environ)
+	for _, kv := range environ {
+		i := strings.Index(kv, "=")
+		if i <= 0 || !strings.HasPrefix(kv[:i], prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(kv[:i], prefix)
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(strings.Replace(name, s.separator, ".", -1))
+		setNested(settings, key, kv[i+1:])
+	}
+	return settings, nil
+}
+
+// mapSource 内存中的配置
+type mapSource struct {
+	name     string
+	settings map[string]interface{}
+}
+
+func (s *mapSource) Name() string {
+	return s.name
+}
+
+func (s *mapSource) Load() (map[string]interface{}, error) {
+	return copySettings(s.settings), nil
+}
+
+// defaultSources 由 Options 中的文件选项构成的默认配置源：
+// 依次为 ConfigFiles 中的配置文件（未设置时为 ConfigPaths 中查找到的主配置文件）、
+// 每个配置文件之后的 profile 覆盖文件和 ConfigDir 中的配置片段
+func (g *Gconf) defaultSources() []Source {
+	sources := make([]Source, 0)
+	if len(g.options.ConfigFiles) > 0 {
+		for _, f := range g.options.ConfigFiles {
+			sources = append(sources, &fileSource{path: absPath(f.Path), optional: !f.Required})
+		}
+	} else {
+		sources = append(sources, &configFileSource{g: g})
+	}
+	sources = withProfiles(sources, g.profiles)
+	if g.options.ConfigDir != "" {
+		sources = append(sources, DirSource(g.options.ConfigDir, g.options.ConfigDirGlob))
+	}
+	return sources
+}
+
+// watchSources 监听所有可监听的配置源，配置源变化时重新加载配置，直到配置实例关闭
+func (g *Gconf) watchSources() {
+	var watchable []WatchableSource
+	for _, src := range g.sources {
+		if ws, ok := src.(WatchableSource); ok {
+			watchable = append(watchable, ws)
+		}
+	}
+	if len(watchable) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-g.done
+		cancel()
+	}()
+	for _, ws := range watchable {
+		go func(ws WatchableSource) {
+			err := ws.Watch(ctx, func() {
+				g.scheduleReload(fsnotify.Event{Name: ws.Name()})
+			})
+			if err != nil && ctx.Err() == nil {
+				g.reloadFailed(fmt.Errorf("gconf: 监听配置源 %s 出错: %w", ws.Name(), err))
+			}
+		}(ws)
+	}
+}
+
+// Layers 返回当前生效的配置层名称，按优先级从高到低排列
+// 依次为 Set 的值（override）、命令行中设置的 flag（flag）、环境变量（env）和 .env 变量（dotenv，顺序见 WithDotEnvOverride）、
+// 按 WithSources 倒序排列的配置源、默认值（default）以及未设置的 flag 的默认值（flag-default），
+// 没有内容的 override、flag、env、dotenv 和 default 层不会列出
+func (g *Gconf) Layers() []string {
+	if g.parent != nil {
+		return g.parent.Layers()
+	}
+
+	g.mu.RLock()
+	hasOverrides := len(g.layers.overrides) > 0
+	hasEnv := g.options.AutomaticEnv || len(g.layers.envBindings) > 0
+	hasDotEnv := hasEnv && len(g.dotenv) > 0
+	hasDefaults := len(g.layers.defaults) > 0
+	g.mu.RUnlock()
+	hasFlags := len(flagBindings(g.options)) > 0
+
+	layers := make([]string, 0, len(g.sources)+6)
+	if hasOverrides {
+		layers = append(layers, "override")
+	}
+	if hasFlags {
+		layers = append(layers, "flag")
+	}
+	switch {
+	case hasDotEnv && g.options.DotEnvOverride:
+		layers = append(layers, "dotenv", "env")
+	case hasDotEnv:
+		layers = append(layers, "env", "dotenv")
+	case hasEnv:
+		layers = append(layers, "env")
+	}
+	// 配置源在创建后不再变化，Name 可能需要加锁，因此不在持有锁时调用
+	for i := len(g.sources) - 1; i >= 0; i-- {
+		layers = append(layers, g.sources[i].Name())
+	}
+	if hasDefaults {
+		layers = append(layers, "default")
+	}
+	if hasFlags {
+		layers = append(layers, "flag-default")
+	}
+	return layers
+}
+
+// readFiles 读取并依次合并多个配置文件及其引用的配置文件，configType 为扩展名无法识别时使用的格式
+func readFiles(files []string, configType string) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		err = loadIncludes(file, data, configType, nil, func(_ string, _ []byte, parsed map[string]interface{}) {
+			mergeSettings(settings, parsed)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return settings, nil
+}
+
+// setNested 按 . 分隔的配置键在配置树中设置值
+func setNested(settings map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	m := settings
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := m[part].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[part] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = value
+}
@@ -0,0 +1,132 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySource 可以修改并通知变化的测试配置源
+type memorySource struct {
+	mu       sync.Mutex
+	settings map[string]interface{}
+	changed  chan struct{}
+}
+
+func (s *memorySource) Name() string {
+	return "memory"
+}
+
+func (s *memorySource) Load() (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copySettings(s.settings), nil
+}
+
+func (s *memorySource) Watch(ctx context.Context, changed func()) error {
+	for {
+		select {
+		case <-s.changed:
+			changed()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *memorySource) set(key string, value interface{}) {
+	s.mu.Lock()
+	setNested(s.settings, key, value)
+	s.mu.Unlock()
+	s.changed <- struct{}{}
+}
+
+func TestSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "app.yaml")
+	writeFile(t, file, "server:\n  host: file.local\n  port: 8080\nname: app\n")
+
+	os.Setenv("GCONFSRC__SERVER__PORT", "9090")
+	defer os.Unsetenv("GCONFSRC__SERVER__PORT")
+
+	mem := &memorySource{
+		settings: map[string]interface{}{"name": "memory"},
+		changed:  make(chan struct{}),
+	}
+	conf, err := New(
+		WithSources(
+			MapSource("defaults", map[string]interface{}{
+				"server": map[string]interface{}{"host": "localhost", "timeout": "5s"},
+			}),
+			FileSource(file),
+			EnvSource("GCONFSRC", "__"),
+			mem,
+		),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	if v := conf.GetString("server.timeout"); v != "5s" {
+		t.Errorf("应读取最低层配置源的值: 期望 5s，得到 %s", v)
+	}
+	if v := conf.GetString("server.host"); v != "file.local" {
+		t.Errorf("文件应覆盖默认值: 期望 file.local，得到 %s", v)
+	}
+	if v := conf.GetInt("server.port"); v != 9090 {
+		t.Errorf("环境变量应覆盖文件: 期望 9090，得到 %d", v)
+	}
+	if v := conf.GetString("name"); v != "memory" {
+		t.Errorf("最后的配置源优先级最高: 期望 memory，得到 %s", v)
+	}
+	if v := conf.ConfigFileUsed(); v != file {
+		t.Errorf("期望使用 %s，得到 %s", file, v)
+	}
+
+	conf.Set("name", "override")
+	want := []string{"override", "memory", "env:GCONFSRC", "file:" + file, "defaults"}
+	if layers := conf.Layers(); !reflect.DeepEqual(layers, want) {
+		t.Errorf("配置层: 期望 %v，得到 %v", want, layers)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+	mem.set("server.host", "memory.local")
+	select {
+	case e := <-events:
+		if c, ok := e.Change("server.host"); !ok || c.NewValue != "memory.local" {
+			t.Errorf("期望 server.host 变为 memory.local: %+v", e.Changes)
+		}
+		if e.Event.Name != "memory" {
+			t.Errorf("事件应指向变化的配置源，得到 %s", e.Event.Name)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待配置源变化超时")
+	}
+}
+
+func TestDefaultSources(t *testing.T) {
+	conf, err := New(WithConfigPaths(os.TempDir()), WithConfigName("gconf-missing"), WithConfigDir("conf.d", "*.yaml"))
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	want := []string{"dir:" + filepath.Join(absPath("conf.d"), "*.yaml"), "file:gconf-missing"}
+	if layers := conf.Layers(); !reflect.DeepEqual(layers, want) {
+		t.Errorf("默认配置源: 期望 %v，得到 %v", want, layers)
+	}
+}
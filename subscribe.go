@@ -0,0 +1,120 @@
+package gconf
+
+import (
+	"context"
+	"sync"
+)
+
+// WatchBufferSize Watch 返回的通道的缓冲大小
+const WatchBufferSize = 16
+
+// subscription 一个 Watch 订阅
+type subscription struct {
+	// prefix 在子配置视图上订阅时为视图的前缀
+	prefix   string
+	patterns []string
+
+	mu     sync.Mutex
+	ch     chan ChangeEvent
+	closed bool
+}
+
+// Watch 返回一个接收配置变化事件的通道，ctx 结束或配置实例关闭时通道被关闭
+// 指定 keys 时只接收匹配的配置项变化（规则见 MatchKey），事件只包含匹配的变化；不指定时接收所有变化。
+//
+// 通道的缓冲大小为 WatchBufferSize，发送事件永远不会阻塞重新加载：缓冲已满时丢弃最旧的未读事件，
+// 因此消费较慢时可能错过中间的事件，但总能收到最新的事件。需要完整状态时应直接读取配置或使用 Snapshot
+func (g *Gconf) Watch(ctx context.Context, keys ...string) <-chan ChangeEvent {
+	s := &subscription{
+		prefix:   g.prefix,
+		patterns: keys,
+		ch:       make(chan ChangeEvent, WatchBufferSize),
+	}
+	if g.parent != nil {
+		g = g.parent
+	}
+
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		s.close()
+		return s.ch
+	}
+	g.subscriptions = append(g.subscriptions, s)
+	g.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-g.done:
+		}
+		g.unsubscribe(s)
+		s.close()
+	}()
+	return s.ch
+}
+
+// unsubscribe 移除订阅
+func (g *Gconf) unsubscribe(s *subscription) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	subs := make([]*subscription, 0, len(g.subscriptions))
+	for _, sub := range g.subscriptions {
+		if sub != s {
+			subs = append(subs, sub)
+		}
+	}
+	g.subscriptions = subs
+}
+
+// publish 将事件发送给所有订阅
+func (g *Gconf) publish(ev ChangeEvent) {
+	g.mu.RLock()
+	subs := g.subscriptions
+	g.mu.RUnlock()
+
+	for _, s := range subs {
+		s.send(ev)
+	}
+}
+
+// send 发送事件，缓冲已满时丢弃最旧的事件，不会阻塞
+func (s *subscription) send(ev ChangeEvent) {
+	if s.prefix != "" {
+		if ev = trimEvent(ev, s.prefix); !ev.HasChanges() {
+			return
+		}
+	}
+	if len(s.patterns) > 0 {
+		if ev = ev.Filter(s.patterns...); !ev.HasChanges() {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for {
+		select {
+		case s.ch <- ev:
+			return
+		default:
+		}
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+// close 关闭通道
+func (s *subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
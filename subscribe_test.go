@@ -0,0 +1,87 @@
+package gconf
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatchChannel(t *testing.T) {
+	conf, _ := New()
+	defer conf.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	all := conf.Watch(ctx)
+	db := conf.Watch(ctx, "database")
+
+	conf.notify(ChangeEvent{Changes: []KeyChange{
+		{Key: "server.port", Type: ChangeModified, OldValue: 8080, NewValue: 9090},
+	}})
+	conf.notify(ChangeEvent{Changes: []KeyChange{
+		{Key: "database.host", Type: ChangeModified, OldValue: "a", NewValue: "b"},
+		{Key: "server.port", Type: ChangeModified, OldValue: 9090, NewValue: 8080},
+	}})
+
+	if e := <-all; e.Keys()[0] != "server.port" {
+		t.Errorf("期望 server.port，得到 %v", e.Keys())
+	}
+	if e := <-all; len(e.Changes) != 2 {
+		t.Errorf("未指定键时应收到全部变化: %v", e.Keys())
+	}
+	if e := <-db; len(e.Changes) != 1 || e.Changes[0].Key != "database.host" {
+		t.Errorf("应只收到 database 下的变化: %v", e.Keys())
+	}
+	select {
+	case e := <-db:
+		t.Errorf("不相关的变化不应发送: %v", e.Keys())
+	default:
+	}
+
+	cancel()
+	for _, ch := range []<-chan ChangeEvent{all, db} {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Error("ctx 取消后不应再收到事件")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ctx 取消后通道应被关闭")
+		}
+	}
+}
+
+func TestWatchChannelDropOldest(t *testing.T) {
+	conf, _ := New()
+
+	ch := conf.Watch(context.Background())
+	total := WatchBufferSize + 5
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < total; i++ {
+			conf.notify(ChangeEvent{Changes: []KeyChange{
+				{Key: fmt.Sprintf("key%d", i), Type: ChangeAdded, NewValue: i},
+			}})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("消费者未读取时不应阻塞通知")
+	}
+
+	first := <-ch
+	if want := fmt.Sprintf("key%d", total-WatchBufferSize); first.Changes[0].Key != want {
+		t.Errorf("缓冲已满时应丢弃最旧的事件: 期望 %s，得到 %s", want, first.Changes[0].Key)
+	}
+
+	conf.Close()
+	n := 1
+	for range ch {
+		n++
+	}
+	if n != WatchBufferSize {
+		t.Errorf("期望缓冲 %d 个事件，得到 %d", WatchBufferSize, n)
+	}
+}
@@ -0,0 +1,114 @@
+package gconf
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// Validator 配置校验函数
+// settings 为重新加载后将要生效的完整配置（包含默认值、环境变量和 Set 的值），
+// 返回错误时本次重新加载被放弃，继续使用上一次有效的配置
+type Validator func(settings map[string]interface{}) error
+
+// ReloadError 重新加载配置失败的错误
+type ReloadError struct {
+	// 重新加载的配置文件
+	File string
+	// 失败原因（读取、解析或校验错误）
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *ReloadError) Error() string {
+	return fmt.Sprintf("gconf: 重新加载配置文件 %s 失败，继续使用上一次有效的配置: %v", e.File, e.Err)
+}
+
+// Unwrap 返回失败原因
+func (e *ReloadError) Unwrap() error {
+	return e.Err
+}
+
+// WithValidator 添加配置校验函数，重新加载的配置只有通过所有校验后才会生效
+func WithValidator(fn Validator) Option {
+	return func(o *Options) {
+		o.Validators = append(o.Validators, fn)
+	}
+}
+
+// WithOnReloadError 设置重新加载失败时的回调，监听配置文件出错时同样通过它报告
+func WithOnReloadError(fn func(error)) Option {
+	return func(o *Options) {
+		o.OnReloadError = fn
+	}
+}
+
+// AddValidator 添加配置校验函数，重新加载的配置只有通过所有校验后才会生效
+func (g *Gconf) AddValidator(fn Validator) {
+	if g.parent != nil {
+		g.parent.AddValidator(fn)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.validators = append(g.validators, fn)
+}
+
+// OnReloadError 注册重新加载失败时的回调函数
+// 回调在触发重新加载的 goroutine 中按注册顺序执行，其中可以调用 Reload、Revert 或 ReadInConfig
+func (g *Gconf) OnReloadError(fn func(error)) {
+	if g.parent != nil {
+		g.parent.OnReloadError(fn)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return
+	}
+	g.reloadErrorHandlers = append(g.reloadErrorHandlers, fn)
+}
+
+// validate 依次执行所有校验函数，返回第一个错误
+func (g *Gconf) validate(settings map[string]interface{}) error {
+	g.mu.RLock()
+	validators := g.validators
+	g.mu.RUnlock()
+
+	for _, fn := range validators {
+		if err := fn(settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reloadFailed 记录并通知重新加载失败，先执行 WithOnReloadError 设置的回调，再按注册顺序执行其他回调
+// 调用方不能持有加载锁，回调中可以再次重新加载
+func (g *Gconf) reloadFailed(err error) {
+	if g.options.Debug {
+		log.Printf("[gconf] %v", err)
+	}
+
+	if g.options.OnReloadError != nil {
+		g.callErrorHandler(g.options.OnReloadError, err)
+	}
+
+	g.mu.RLock()
+	handlers := g.reloadErrorHandlers
+	g.mu.RUnlock()
+
+	for _, handler := range handlers {
+		g.callErrorHandler(handler, err)
+	}
+}
+
+// callErrorHandler 执行错误回调，回调 panic 时恢复，不会导致进程崩溃
+func (g *Gconf) callErrorHandler(handler func(error), err error) {
+	defer func() {
+		if p := recover(); p != nil && g.options.Debug {
+			log.Printf("[gconf] 错误回调 panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	handler(err)
+}
@@ -0,0 +1,177 @@
+package gconf
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReloadRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+		WithValidator(func(settings map[string]interface{}) error {
+			server, _ := settings["server"].(map[string]interface{})
+			if server["port"] == 0 {
+				return errors.New("server.port 不能为 0")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.SetDefault("server.host", "localhost")
+
+	reloadErrors := make(chan error, 10)
+	conf.OnReloadError(func(err error) {
+		reloadErrors <- err
+	})
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	expectReloadError := func() {
+		t.Helper()
+		select {
+		case err := <-reloadErrors:
+			var reloadErr *ReloadError
+			if !errors.As(err, &reloadErr) || reloadErr.File != file {
+				t.Errorf("期望 ReloadError，得到 %v", err)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待重新加载失败回调超时")
+		}
+		if v := conf.GetInt("server.port"); v != 8080 {
+			t.Errorf("重新加载失败后应保留原配置: 期望 8080，得到 %d", v)
+		}
+	}
+
+	// 语法错误
+	writeFile(t, file, "server: [port: 1\n")
+	expectReloadError()
+
+	// 校验失败
+	writeFile(t, file, "server:\n  port: 0\n")
+	expectReloadError()
+
+	// 合法配置正常生效
+	writeFile(t, file, "server:\n  port: 9090\n")
+	select {
+	case ev := <-events:
+		if c, ok := ev.Change("server.port"); !ok || c.NewValue != 9090 {
+			t.Errorf("server.port 变化不正确: %+v", ev.Changes)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待配置变化事件超时")
+	}
+	if v := conf.GetString("server.host"); v != "localhost" {
+		t.Errorf("默认值应保留: 期望 'localhost'，得到 '%s'", v)
+	}
+}
+
+func TestReloadErrorHandlerPanic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithOnReloadError(func(err error) {
+			panic("选项回调 panic")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	reloadErrors := make(chan error, 1)
+	conf.OnReloadError(func(err error) {
+		panic("回调 panic")
+	})
+	conf.OnReloadError(func(err error) {
+		reloadErrors <- err
+	})
+
+	writeFile(t, file, "server: [port: 1\n")
+	if _, err := conf.Reload(context.Background()); err == nil {
+		t.Error("语法错误时应返回错误")
+	}
+	select {
+	case <-reloadErrors:
+	case <-time.After(3 * time.Second):
+		t.Fatal("回调 panic 后其他回调仍应执行")
+	}
+}
+
+func TestReloadErrorHandlerReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	// 语法错误每次加载都会报告，只在第一次回调中重新加载，避免递归
+	var conf *Gconf
+	var nested int32
+	handled := make(chan struct{}, 10)
+	conf, err = New(
+		WithConfigPaths(dir),
+		WithOnReloadError(func(err error) {
+			if atomic.AddInt32(&nested, 1) == 1 {
+				// 错误回调中再次加载不应死锁
+				_ = conf.ReadInConfig()
+				_, _ = conf.Reload(context.Background())
+			}
+			handled <- struct{}{}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.OnReloadError(func(err error) {
+		handled <- struct{}{}
+	})
+
+	writeFile(t, file, "server: [port: 1\n")
+	done := make(chan struct{})
+	go func() {
+		_, _ = conf.Reload(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("错误回调中调用 Reload 导致死锁")
+	}
+	// 外层和回调中的 Reload 各报告一次
+	if len(handled) != 4 {
+		t.Errorf("期望错误回调执行 4 次，得到 %d", len(handled))
+	}
+}
@@ -0,0 +1,62 @@
+package gconf
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// fullKey 将视图中的配置键转换为父实例中的完整配置键
+func (g *Gconf) fullKey(key string) string {
+	if key == "" {
+		return g.prefix
+	}
+	return g.prefix + "." + strings.ToLower(key)
+}
+
+// viewKeys 返回父实例中前缀下的所有配置键（不包含前缀）
+func (g *Gconf) viewKeys() []string {
+	keys := make([]string, 0)
+	for _, key := range g.parent.AllKeys() {
+		if strings.HasPrefix(key, g.prefix+".") {
+			keys = append(keys, strings.TrimPrefix(key, g.prefix+"."))
+		}
+	}
+	return keys
+}
+
+// viewSettings 返回父实例中前缀下的配置树
+// 不直接使用 Get(prefix)：viper 只返回找到的第一层配置中的子树，会丢失其他层（例如默认值）中的配置项
+func (g *Gconf) viewSettings() map[string]interface{} {
+	settings := g.parent.AllSettings()
+	for _, part := range strings.Split(g.prefix, ".") {
+		sub, ok := settings[part].(map[string]interface{})
+		if !ok {
+			return make(map[string]interface{})
+		}
+		settings = sub
+	}
+	return settings
+}
+
+// viewViper 用视图的配置树构建临时 viper 实例，用于解析结构体
+func (g *Gconf) viewViper() *viper.Viper {
+	v := viper.New()
+	_ = v.MergeConfigMap(g.viewSettings())
+	return v
+}
+
+// trimEvent 筛选 prefix 下的配置项变化并去掉配置键的前缀，prefix 为空时原样返回
+func trimEvent(e ChangeEvent, prefix string) ChangeEvent {
+	if prefix == "" {
+		return e
+	}
+	trimmed := ChangeEvent{Event: e.Event}
+	for _, c := range e.Changes {
+		if strings.HasPrefix(c.Key, prefix+".") {
+			c.Key = strings.TrimPrefix(c.Key, prefix+".")
+			trimmed.Changes = append(trimmed.Changes, c)
+		}
+	}
+	return trimmed
+}
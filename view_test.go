@@ -0,0 +1,101 @@
+package gconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubView(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "database:\n  host: localhost\n  pool:\n    size: 10\nserver:\n  port: 8080\n")
+
+	os.Setenv("GCONFVIEW_DATABASE_USER", "admin")
+	defer os.Unsetenv("GCONFVIEW_DATABASE_USER")
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithAutomaticEnv(true),
+		WithEnvPrefix("GCONFVIEW"),
+		WithEnvKeyReplacer(".", "_"),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	conf.SetDefault("database.port", 3306)
+	conf.RegisterAlias("database.addr", "database.host")
+
+	db := conf.Sub("database")
+	if db == nil {
+		t.Fatal("Sub 返回 nil")
+	}
+	if conf.Sub("missing") != nil {
+		t.Error("子配置树不存在时应返回 nil")
+	}
+	if conf.Sub("database.port") != nil || db.Sub("port") != nil {
+		t.Error("普通的配置值不是子配置树，应返回 nil")
+	}
+
+	if v := db.GetInt("port"); v != 3306 {
+		t.Errorf("视图应读取父实例的默认值: 期望 3306，得到 %d", v)
+	}
+	if v := db.GetString("user"); v != "admin" {
+		t.Errorf("视图应读取带前缀的环境变量: 期望 admin，得到 %s", v)
+	}
+	if v := db.GetString("addr"); v != "localhost" {
+		t.Errorf("视图应支持父实例的别名: 期望 localhost，得到 %s", v)
+	}
+	if v := db.Sub("pool").GetInt("size"); v != 10 {
+		t.Errorf("嵌套视图: 期望 10，得到 %d", v)
+	}
+
+	settings := db.AllSettings()
+	if settings["host"] != "localhost" || settings["port"] != 3306 {
+		t.Errorf("AllSettings 应合并所有配置层: %v", settings)
+	}
+	var cfg struct {
+		Host string
+		Port int
+	}
+	if err := db.Unmarshal(&cfg); err != nil || cfg.Host != "localhost" || cfg.Port != 3306 {
+		t.Errorf("Unmarshal 结果不正确: %+v, %v", cfg, err)
+	}
+
+	db.Set("host", "db.example.com")
+	if v := conf.GetString("database.host"); v != "db.example.com" {
+		t.Errorf("视图的 Set 应写入父实例，得到 %s", v)
+	}
+
+	var events []ChangeEvent
+	db.OnChange(func(e ChangeEvent) {
+		events = append(events, e)
+	})
+
+	writeFile(t, file, "database:\n  host: localhost\n  pool:\n    size: 20\nserver:\n  port: 9090\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if v := db.GetInt("pool.size"); v != 20 {
+		t.Errorf("视图应反映重新加载后的配置: 期望 20，得到 %d", v)
+	}
+	if len(events) != 1 || len(events[0].Changes) != 1 || events[0].Changes[0].Key != "pool.size" {
+		t.Errorf("视图的回调应只收到前缀下的变化且不含前缀: %+v", events)
+	}
+
+	writeFile(t, file, "database:\n  host: localhost\n  pool:\n    size: 20\nserver:\n  port: 7070\n")
+	if _, err := conf.Reload(context.Background()); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("前缀之外的变化不应触发视图的回调: %+v", events)
+	}
+}
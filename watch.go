@@ -0,0 +1,351 @@
+package gconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloader 合并短时间内的多次文件事件，并串行执行重新加载
+type reloader struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	event   fsnotify.Event
+	stopped bool
+
+	// loadMu 保证重新加载串行执行，回调通知在释放后进行
+	loadMu sync.Mutex
+	// 上一次加载的配置文件内容摘要，内容不变时跳过重新加载
+	hash string
+	// 上一次加载失败的原因，内容不变时直接返回而不再重复报告
+	err error
+}
+
+// fileWatcher 基于 fsnotify 监听配置文件和配置目录
+//
+// 监听的是文件所在的目录而不是文件本身，以兼容编辑器的原子保存（重命名替换）。
+// Kubernetes 挂载的 ConfigMap/Secret 中，配置文件是指向 ..data/<file> 的符号链接，
+// 更新时只会原子地替换 ..data 链接，文件本身不会产生写事件，因此还会跟踪每个配置文件的真实路径：
+// 真实路径变化即视为配置变化，同时重新监听新的真实目录。
+// 一次替换会产生多个目录事件，但只有 ..data 被替换时真实路径才会变化，
+// 再加上内容摘要去重，每次替换只会触发一次重新加载
+type fileWatcher struct {
+	g       *Gconf
+	watcher *fsnotify.Watcher
+	// 配置片段目录，其中新增的匹配文件也需要触发重新加载
+	configDirs []*dirSource
+	// 每个配置文件当前的真实路径
+	realFiles map[string]string
+}
+
+// watchConfig 监听配置文件和配置目录，文件变化时重新加载配置并通知回调
+func (g *Gconf) watchConfig() error {
+	w := &fileWatcher{
+		g:         g,
+		realFiles: make(map[string]string),
+	}
+	for _, src := range g.sources {
+		if ds, ok := src.(*dirSource); ok {
+			w.configDirs = append(w.configDirs, ds)
+		}
+	}
+
+	dirs := w.dirs()
+	if len(dirs) == 0 {
+		return errors.New("gconf: 没有可监听的配置文件")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+	w.watcher = watcher
+
+	for _, file := range w.files() {
+		w.realFiles[file], _ = filepath.EvalSymlinks(file)
+	}
+
+	g.mu.Lock()
+	g.watcher = w
+	g.mu.Unlock()
+
+	go w.run()
+	return nil
+}
+
+// run 处理文件事件，直到 fsnotify 关闭
+func (w *fileWatcher) run() {
+	defer w.watcher.Close()
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.isConfigEvent(event) {
+				w.g.scheduleReload(event)
+			}
+			w.sync()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			// 出错（例如 inotify 事件队列溢出）时可能丢失了事件：报告错误，重新添加监听目录并强制重新加载，之后继续监听
+			w.g.reloadFailed(fmt.Errorf("gconf: 监听配置文件出错: %w", err))
+			w.sync()
+			w.g.scheduleReload(fsnotify.Event{Name: w.g.ConfigFileUsed()})
+		}
+	}
+}
+
+// files 返回当前已加载的配置文件
+func (w *fileWatcher) files() []string {
+	w.g.mu.RLock()
+	defer w.g.mu.RUnlock()
+	return w.g.files
+}
+
+// dirs 返回需要监听的目录：配置文件所在目录、配置文件真实路径所在目录以及配置目录
+func (w *fileWatcher) dirs() []string {
+	dirs := make([]string, 0)
+	for _, file := range w.files() {
+		dirs = appendUnique(dirs, filepath.Dir(file))
+		if real, err := filepath.EvalSymlinks(file); err == nil {
+			dirs = appendUnique(dirs, filepath.Dir(real))
+		}
+	}
+	for _, ds := range w.configDirs {
+		dirs = appendUnique(dirs, ds.dir)
+	}
+	for _, file := range w.optionalFiles() {
+		if dir := filepath.Dir(file); isDir(dir) {
+			dirs = appendUnique(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// optionalFiles 返回可选的配置文件和 profile 覆盖文件，它们尚不存在时也需要监听所在目录
+func (w *fileWatcher) optionalFiles() []string {
+	files := make([]string, 0)
+	for _, src := range w.g.sources {
+		switch s := src.(type) {
+		case *fileSource:
+			if s.optional {
+				files = append(files, s.path)
+			}
+		case *profileSource:
+			files = append(files, s.candidates()...)
+		}
+	}
+	return files
+}
+
+// sync 重新添加需要监听的目录
+// 被删除的目录会自动从 fsnotify 中移除，符号链接被替换或目录被重建后需要重新添加；
+// 对已监听的目录重复添加不会产生影响
+func (w *fileWatcher) sync() {
+	for _, dir := range w.dirs() {
+		_ = w.watcher.Add(dir)
+	}
+}
+
+// isConfigEvent 判断文件事件是否与配置有关：
+// 1. 已加载的配置文件（或其符号链接指向的文件）被写入、创建、删除或重命名
+// 2. 配置目录中出现、修改或删除了匹配的片段文件，或可选的配置文件、profile 覆盖文件被创建
+// 3. 配置文件的真实路径发生变化
+func (w *fileWatcher) isConfigEvent(event fsnotify.Event) bool {
+	name := filepath.Clean(event.Name)
+
+	relevant := false
+	for _, file := range w.files() {
+		if name == file || name == w.realFiles[file] {
+			relevant = true
+		}
+		current, _ := filepath.EvalSymlinks(file)
+		if current != "" && current != w.realFiles[file] {
+			w.realFiles[file] = current
+			relevant = true
+		}
+	}
+	for _, ds := range w.configDirs {
+		if ds.matches(name) {
+			relevant = true
+		}
+	}
+	if stringInSlice(name, w.optionalFiles()) {
+		relevant = true
+	}
+	return relevant
+}
+
+// scheduleReload 安排一次重新加载
+// 设置了 ReloadDebounce 时，窗口期内的多次事件只会触发一次重新加载，使用最后一次事件
+func (g *Gconf) scheduleReload(e fsnotify.Event) {
+	d := g.options.ReloadDebounce
+	if d <= 0 {
+		g.reloadAsync(e)
+		return
+	}
+
+	r := &g.reloader
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.event = e
+	if r.timer != nil {
+		r.timer.Reset(d)
+		return
+	}
+	r.timer = time.AfterFunc(d, func() {
+		r.mu.Lock()
+		event := r.event
+		r.timer = nil
+		r.mu.Unlock()
+		g.reloadAsync(event)
+	})
+}
+
+// stop 停止尚未执行的重新加载，之后的事件都会被忽略
+func (r *reloader) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// reload 重新读取配置文件，比较前后差异并通知所有回调，返回配置是否发生变化
+// 文件内容与上一次加载时相同（例如只是 touch）则跳过；新内容先解析到暂存副本并通过
+// 所有校验后才会生效，否则保留上一次有效的配置并通过 OnReloadError 报告。
+// 等待所有回调执行结束，配置已生效但有回调执行失败时返回 true 和 *DispatchError；
+// 等待期间 ctx 结束时返回 ctx.Err()，此时配置已生效
+func (g *Gconf) reload(ctx context.Context, e fsnotify.Event) (bool, error) {
+	ev, err := g.load(ctx, e, false)
+	if ev == nil {
+		return false, err
+	}
+	if ctx.Done() == nil {
+		return ev.HasChanges(), g.notify(*ev)
+	}
+
+	// ctx 结束时不再等待回调，回调在后台继续执行
+	result := make(chan error, 1)
+	go func() {
+		result <- g.notify(*ev)
+	}()
+	select {
+	case err := <-result:
+		return ev.HasChanges(), err
+	case <-ctx.Done():
+		return ev.HasChanges(), ctx.Err()
+	}
+}
+
+// reloadAsync 重新加载配置并通过通知队列按顺序通知回调，用于文件事件、轮询和信号触发的重新加载，
+// 执行缓慢的回调不会阻塞之后的重新加载
+func (g *Gconf) reloadAsync(e fsnotify.Event) {
+	_, _ = g.load(context.Background(), e, true)
+}
+
+// load 重新读取配置文件并在校验通过后生效，返回需要通知回调的事件，跳过或失败时为 nil
+// 加载过程串行执行，但不包括回调通知，回调中可以再次调用 Reload 或 Revert；
+// queue 为 true 时在加载锁内将事件加入通知队列，保证通知顺序与加载顺序一致
+func (g *Gconf) load(ctx context.Context, e fsnotify.Event, queue bool) (*ChangeEvent, error) {
+	r := &g.reloader
+	r.loadMu.Lock()
+	// 失败在释放加载锁之后报告，错误回调中可以再次调用 Reload、Revert 或 ReadInConfig
+	var failed error
+	defer func() {
+		r.loadMu.Unlock()
+		if failed != nil {
+			g.reloadFailed(failed)
+		}
+	}()
+
+	if g.isClosed() {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	layer, err := g.loadFiles()
+	if err != nil {
+		err = &ReloadError{File: g.ConfigFileUsed(), Err: err}
+		failed = err
+		return nil, err
+	}
+	if layer.hash == r.hash {
+		if g.options.Debug {
+			log.Printf("[gconf] 配置文件内容未变化，跳过重新加载: %s", e.Name)
+		}
+		return nil, r.err
+	}
+
+	staging, err := g.newStaging(layer)
+	if err == nil {
+		var settings map[string]interface{}
+		if settings, err = g.expandedSettings(staging, layer.dotenv); err == nil {
+			err = g.validate(settings)
+		}
+	}
+	if err != nil {
+		err = &ReloadError{File: layer.mainFile, Err: err}
+		r.hash, r.err = layer.hash, err
+		failed = err
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.hash, r.err = layer.hash, nil
+
+	g.mu.Lock()
+	oldSettings := g.allSettings()
+	g.applyFiles(layer)
+	changes := diffSettings(oldSettings, g.allSettings())
+	g.generation++
+	g.recordRevision(SourceReload, e.Name, changes)
+	g.mu.Unlock()
+
+	if g.options.Debug {
+		log.Printf("[gconf] 配置文件变化: %s, 操作: %s", e.Name, e.Op)
+	}
+
+	// 先更新绑定的结构体，回调中读取到的已是新值
+	g.refreshBindings()
+
+	ev := &ChangeEvent{
+		Event:   e,
+		Changes: changes,
+	}
+	if queue {
+		g.enqueue(*ev)
+	}
+	return ev, nil
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(list []string, s string) []string {
+	if stringInSlice(s, list) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,142 @@
+package gconf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// swapConfigMap 模拟 kubelet 更新 ConfigMap 挂载目录：
+// 写入新的时间戳目录，创建临时链接后原子地重命名为 ..data，最后删除旧目录
+func swapConfigMap(t *testing.T, dir, oldTarget, newTarget, content string) {
+	t.Helper()
+	if err := os.Mkdir(filepath.Join(dir, newTarget), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, newTarget, "config.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(newTarget, filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if oldTarget != "" {
+		if err := os.RemoveAll(filepath.Join(dir, oldTarget)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWatchConfigMapSymlinkSwap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	swapConfigMap(t, dir, "", "..2024_01_01", "port: 1\n")
+	if err := os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+	if v := conf.GetInt("port"); v != 1 {
+		t.Fatalf("期望 1，得到 %d", v)
+	}
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	targets := []string{"..2024_01_01", "..2024_01_02", "..2024_01_03"}
+	for i := 1; i < len(targets); i++ {
+		port := i + 1
+		swapConfigMap(t, dir, targets[i-1], targets[i], fmt.Sprintf("port: %d\n", port))
+
+		select {
+		case ev := <-events:
+			if c, ok := ev.Change("port"); !ok || c.NewValue != port {
+				t.Errorf("第 %d 次替换后 port 变化不正确: %+v", i, ev.Changes)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("第 %d 次替换后等待配置变化事件超时", i)
+		}
+
+		// 每次替换只应触发一次重新加载
+		select {
+		case ev := <-events:
+			t.Errorf("第 %d 次替换触发了多余的事件: %+v", i, ev)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		if v := conf.GetInt("port"); v != port {
+			t.Errorf("第 %d 次替换后期望 %d，得到 %d", i, port, v)
+		}
+	}
+}
+
+func TestWatchError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gconf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "server:\n  port: 8080\n")
+
+	reloadErrors := make(chan error, 10)
+	conf, err := New(
+		WithConfigPaths(dir),
+		WithWatchConfig(true),
+		WithOnReloadError(func(err error) {
+			reloadErrors <- err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("创建配置实例失败: %v", err)
+	}
+	defer conf.Close()
+
+	events := make(chan ChangeEvent, 10)
+	conf.OnChange(func(e ChangeEvent) {
+		events <- e
+	})
+
+	// 模拟 inotify 事件队列溢出
+	conf.watcher.watcher.Errors <- errors.New("queue overflow")
+	select {
+	case err := <-reloadErrors:
+		if !strings.Contains(err.Error(), "queue overflow") {
+			t.Errorf("错误不正确: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("监听出错时应通过 OnReloadError 报告")
+	}
+
+	// 出错后继续监听
+	writeFile(t, file, "server:\n  port: 9090\n")
+	select {
+	case ev := <-events:
+		if c, ok := ev.Change("server.port"); !ok || c.NewValue != 9090 {
+			t.Errorf("server.port 变化不正确: %+v", ev.Changes)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("监听出错后应继续监听")
+	}
+}